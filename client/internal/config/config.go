@@ -0,0 +1,166 @@
+// Package config loads and saves the client's
+// persistent settings
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultServer is played on when no other server is configured
+const DefaultServer = "ws://localhost:3000/ws/"
+
+// Config holds the settings remembered between runs
+type Config struct {
+	Nickname string `json:"nickname,omitempty"`
+	PlayerID string `json:"playerId,omitempty"` // identifies the player to servers, so ratings follow them
+
+	Server   string    `json:"server,omitempty"`   // played on when no profile is chosen
+	Profile  string    `json:"profile,omitempty"`  // the profile last switched to
+	Profiles []Profile `json:"profiles,omitempty"` // named servers to switch between
+
+	NoMouse     bool `json:"noMouse,omitempty"`     // leave the mouse to the terminal
+	NoAltScreen bool `json:"noAltScreen,omitempty"` // draw below the prompt instead of taking over the terminal
+}
+
+// Profile is a named server the player can switch to
+type Profile struct {
+	Name   string `json:"name"`
+	Server string `json:"server"`
+}
+
+// ServerFor returns the server URL of the named profile, or of the
+// config itself if name is empty
+func (c Config) ServerFor(name string) (string, error) {
+	if name == "" {
+		if c.Server == "" {
+			return DefaultServer, nil
+		}
+		return NormalizeServer(c.Server)
+	}
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return NormalizeServer(p.Server)
+		}
+	}
+	return "", fmt.Errorf("no server profile named %q", name)
+}
+
+// NormalizeServer turns a server address as a player would write it,
+// such as "example.com:3000" or "https://example.com", into the
+// websocket URL the client dials
+func NormalizeServer(server string) (string, error) {
+	if !strings.Contains(server, "://") {
+		server = "ws://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported server scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("server %q has no host", server)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/ws/"
+	}
+	return u.String(), nil
+}
+
+// NewPlayerID returns a random player ID. It is never shown to other
+// players, so it doubles as proof of who the player is.
+func NewPlayerID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// File overrides where the config file lives when set
+var File string
+
+// Path returns where the config file lives, by default under
+// the user's config directory ($XDG_CONFIG_HOME on Linux)
+func Path() (string, error) {
+	if File != "" {
+		return File, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "teletyperacer", "config.json"), nil
+}
+
+// Load reads the config file. A missing file is not an
+// error and yields the zero Config.
+func Load() (Config, error) {
+	var cfg Config
+	path, err := Path()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// Save writes cfg to the config file, creating its directory
+// if needed. Only the user may read the file, since it holds
+// their player ID.
+func Save(cfg Config) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Writing a new file and moving it into place also tightens the
+	// permissions of a file saved by an older version
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Update applies change to the saved config. Settings overridden for
+// a single run are left out, since the file is read afresh.
+func Update(change func(*Config)) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	change(&cfg)
+	return Save(cfg)
+}
@@ -0,0 +1,145 @@
+// Package chat defines a chat panel with scrollback and a text input
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Config struct {
+	Placeholder string
+	CharLimit   int
+	Height      int // number of messages shown at once
+	Width       int
+	Hint        string // shown in place of the input while it is unfocused
+}
+
+// Line is a single message in the panel
+type Line struct {
+	Name  string
+	Color lipgloss.Color
+	Text  string
+	At    time.Time
+}
+
+type Model struct {
+	textInput textinput.Model
+	lines     []Line
+	offset    int // messages scrolled back from the newest
+	config    Config
+}
+
+var _ tea.Model = Model{}
+
+func NewChat(config Config) Model {
+	ti := textinput.New()
+	ti.Placeholder = config.Placeholder
+	ti.CharLimit = config.CharLimit
+	ti.Width = config.Width - 4
+	ti.Prompt = "> "
+
+	return Model{
+		textInput: ti,
+		config:    config,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Focus moves keyboard input into the chat
+func (m Model) Focus() (Model, tea.Cmd) {
+	return m, m.textInput.Focus()
+}
+
+func (m Model) Focused() bool {
+	return m.textInput.Focused()
+}
+
+// Append adds messages to the bottom of the panel, keeping the
+// view still if the player has scrolled back
+func (m Model) Append(lines ...Line) Model {
+	m.lines = append(m.lines, lines...)
+	if m.offset > 0 {
+		m.offset += len(lines)
+	}
+	return m
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyPgUp:
+		m.offset = min(m.offset+m.config.Height, max(len(m.lines)-m.config.Height, 0))
+		return m, nil
+	case tea.KeyPgDown:
+		m.offset = max(m.offset-m.config.Height, 0)
+		return m, nil
+	}
+	if !m.Focused() {
+		return m, nil
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyEnter:
+		text := strings.TrimSpace(m.textInput.Value())
+		m.textInput.Reset()
+		if text == "" {
+			return m, nil
+		}
+		m.offset = 0
+		return m, func() tea.Msg { return SendMsg{Text: text} }
+	case tea.KeyEsc:
+		m.textInput.Blur()
+		return m, func() tea.Msg { return BlurMsg{} }
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) View() string {
+	end := len(m.lines) - m.offset
+	start := max(end-m.config.Height, 0)
+
+	timeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	var rows []string
+	for _, line := range m.lines[start:end] {
+		name := lipgloss.NewStyle().Foreground(line.Color).Bold(true).Render(line.Name)
+		rows = append(rows, fmt.Sprintf("%s %s: %s", timeStyle.Render(line.At.Format("15:04")), name, line.Text))
+	}
+	for len(rows) < m.config.Height {
+		rows = append([]string{""}, rows...)
+	}
+
+	var footer string
+	if m.Focused() {
+		footer = m.textInput.View()
+	} else {
+		footer = timeStyle.Render(m.config.Hint)
+	}
+	if m.offset > 0 {
+		footer += timeStyle.Render(fmt.Sprintf(" (%d newer)", m.offset))
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.ANSIColor(4)).
+		Padding(0, 1).
+		Width(m.config.Width).
+		Align(lipgloss.Left).
+		Render(strings.Join(rows, "\n") + "\n\n" + footer)
+}
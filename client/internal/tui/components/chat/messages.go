@@ -0,0 +1,9 @@
+package chat
+
+// SendMsg is sent when the player submits a chat message
+type SendMsg struct {
+	Text string
+}
+
+// BlurMsg is sent when the player leaves the chat input
+type BlurMsg struct{}
@@ -15,6 +15,8 @@ type Config struct {
 	SubmittedLabel string
 	SubmittedText  string
 	CharLimit      int
+	PreserveCase   bool // keep the input as typed instead of uppercasing it
+	Secret         bool // hide what is typed, as for a password
 }
 
 type Model struct {
@@ -32,6 +34,9 @@ func NewInput(config Config) Model {
 	ti.Placeholder = config.Placeholder
 	ti.Focus()
 	ti.CharLimit = config.CharLimit
+	if config.Secret {
+		ti.EchoMode = textinput.EchoPassword
+	}
 	ti.Width = 30
 
 	return Model{
@@ -56,31 +61,39 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.Type {
 		case tea.KeyEnter:
 			m.submitted = true
+			value := m.textInput.Value()
+			if !m.config.PreserveCase {
+				value = strings.ToUpper(value)
+			}
 			return m, func() tea.Msg {
-				return SubmitMsg{Value: strings.ToUpper(m.textInput.Value())}
+				return SubmitMsg{Value: value}
 			}
 		case tea.KeyEsc:
 			return m, func() tea.Msg { return HideMsg{} }
 		default:
 			var cmd tea.Cmd
 			m.textInput, cmd = m.textInput.Update(msg)
-			// Uppercase the input after updating
-			currentValue := m.textInput.Value()
-			if currentValue != strings.ToUpper(currentValue) {
-				m.textInput.SetValue(strings.ToUpper(currentValue))
-			}
+			m.normalizeCase()
 			return m, cmd
 		}
 	}
 
 	var cmd tea.Cmd
 	m.textInput, cmd = m.textInput.Update(msg)
-	// Uppercase the input after updating
+	m.normalizeCase()
+	return m, cmd
+}
+
+// normalizeCase uppercases the input after an update
+// unless the input preserves case
+func (m *Model) normalizeCase() {
+	if m.config.PreserveCase {
+		return
+	}
 	currentValue := m.textInput.Value()
 	if currentValue != strings.ToUpper(currentValue) {
 		m.textInput.SetValue(strings.ToUpper(currentValue))
 	}
-	return m, cmd
 }
 
 func (m Model) View() string {
@@ -10,5 +10,8 @@ type AccuracyMsg struct {
 
 // ProgressMsg contains the typing progress
 type ProgressMsg struct {
-	Progress float64
+	Progress float64 // Percentage of the text typed
+	Position int     // Cursor position in the text
+	WPM      float64
+	Errors   int
 }
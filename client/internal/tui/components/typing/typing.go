@@ -63,6 +63,21 @@ func NewTyping(text string) Model {
 	}
 }
 
+// NewTypingAt creates a typing model with the first position
+// characters already typed, for resuming an interrupted race
+func NewTypingAt(text string, position int) Model {
+	m := NewTyping(text)
+	if position > len(m.runes) {
+		position = len(m.runes)
+	}
+	if position > 0 {
+		m.inputBuffer = append(m.inputBuffer, m.runes[:position]...)
+		m.cursor = position
+		m.completed = position >= len(m.runes)
+	}
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
 	return nil
 }
@@ -81,6 +96,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.lastKeyTime = time.Now()
 				m.updateWPM()
 				m.wpmHistory = append(m.wpmHistory, m.wpm)
+				return m, m.progressCmd()
 			}
 		case tea.KeySpace:
 			if m.cursor < len(m.runes) {
@@ -99,6 +115,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.cursor >= len(m.runes) {
 					m.completed = true
 				}
+				return m, m.progressCmd()
 			}
 		case tea.KeyRunes:
 			if m.cursor < len(m.runes) {
@@ -117,6 +134,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.cursor >= len(m.runes) {
 					m.completed = true
 				}
+				return m, m.progressCmd()
 			}
 		}
 	case tea.WindowSizeMsg:
@@ -198,6 +216,22 @@ func styleAllRunes(runes []rune, style lipgloss.Style) string {
 	return acc.String()
 }
 
+// progressCmd reports the current progress, followed
+// by TypingCompletedMsg once the text is finished
+func (m Model) progressCmd() tea.Cmd {
+	progress := ProgressMsg{
+		Progress: m.GetProgress(),
+		Position: m.cursor,
+		WPM:      m.wpm,
+		Errors:   len(m.mistakes),
+	}
+	cmd := func() tea.Msg { return progress }
+	if m.completed {
+		return tea.Sequence(cmd, func() tea.Msg { return TypingCompletedMsg{} })
+	}
+	return cmd
+}
+
 // Getters for external use
 func (m Model) IsCompleted() bool {
 	return m.completed
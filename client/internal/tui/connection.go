@@ -0,0 +1,211 @@
+package root
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gorilla/websocket"
+
+	"github.com/givensuman/teletyperacer/client/internal/tui/screens"
+	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+const (
+	// dialTimeout bounds a single attempt to reach the server
+	dialTimeout = 10 * time.Second
+	// Reconnect attempts back off exponentially from minReconnectDelay,
+	// up to maxReconnectDelay
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+var dialer = &websocket.Dialer{
+	Proxy:            http.ProxyFromEnvironment,
+	HandshakeTimeout: dialTimeout,
+}
+
+// dialedMsg reports how dialing the server went
+type dialedMsg struct {
+	gen  int // the dial this answers, stale if the client has moved on
+	conn *websocket.Conn
+	err  error
+}
+
+// redialMsg tries the server again once a backoff has passed
+type redialMsg struct {
+	gen int
+}
+
+// rejoin is the room to return to once the client reconnects
+type rejoin struct {
+	token      string // session to resume, which keeps a held race slot
+	code       string
+	password   string
+	spectating bool
+}
+
+// dialServer connects to the server in the background
+func dialServer(server string, gen int) tea.Cmd {
+	return func() tea.Msg {
+		conn, _, err := dialer.Dial(server, nil)
+		return dialedMsg{gen: gen, conn: conn, err: err}
+	}
+}
+
+// reconnectDelay is how long to wait before the given attempt at
+// reconnecting, with some jitter so that clients dropped together
+// don't all return at once
+func reconnectDelay(attempt int) time.Duration {
+	delay := maxReconnectDelay
+	if attempt < 6 {
+		delay = min(minReconnectDelay<<(attempt-1), maxReconnectDelay)
+	}
+	jitter := time.Duration(rand.Int64N(int64(delay) / 4))
+	return delay - delay/8 + jitter
+}
+
+// redial abandons any dial in progress and dials the server afresh
+func (m Model) redial() (Model, tea.Cmd) {
+	m.gen++
+	m.retryAt = time.Time{}
+	return m, dialServer(m.server, m.gen)
+}
+
+// retryLater dials the server again once the backoff for the
+// current attempt has passed
+func (m Model) retryLater() (Model, tea.Cmd) {
+	delay := reconnectDelay(m.attempt)
+	m.retryAt = time.Now().Add(delay)
+	gen := m.gen
+	return m, tea.Tick(delay, func(time.Time) tea.Msg { return redialMsg{gen: gen} })
+}
+
+// dialed starts using a new connection, or schedules another attempt
+// if the server could not be reached
+func (m Model) dialed(msg dialedMsg) (tea.Model, tea.Cmd) {
+	if msg.gen != m.gen {
+		if msg.conn != nil {
+			msg.conn.Close()
+		}
+		return m, nil
+	}
+
+	if msg.err != nil {
+		m.connectionStatus = categorizeConnectionError(msg.err)
+		var retry tea.Cmd
+		if m.connectionStatus != types.ClientError {
+			// A bad address won't fix itself, but anything else might
+			m.attempt++
+			m, retry = m.retryLater()
+		}
+		var cmd tea.Cmd
+		m.home, cmd = m.home.Update(types.ConnectionStatusMsg{Status: m.connectionStatus, RetryAt: m.retryAt})
+		return m, tea.Batch(cmd, retry)
+	}
+
+	// Stay connecting until the server welcomes us
+	m.conn = msg.conn
+	m.done = make(chan struct{})
+	m.connectionStatus = types.Connecting
+	var cmd tea.Cmd
+	m.home, cmd = m.home.Update(types.ConnectionStatusMsg{Status: types.Connecting})
+	m.connect()
+	return m, cmd
+}
+
+// connectionLost redials after the connection drops, staying in the
+// current room so it can be rejoined. A connection that was up is
+// redialed straight away; one that dropped before the server welcomed
+// us backs off like a failed dial.
+func (m Model) connectionLost(msg types.ConnectionStatusMsg) (tea.Model, tea.Cmd) {
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+	if m.rejoin == nil {
+		m.rejoin = m.currentRoom()
+	}
+	m.capabilities = nil
+
+	m.attempt++
+	var dial tea.Cmd
+	if m.attempt == 1 {
+		m, dial = m.redial()
+	} else {
+		m, dial = m.retryLater()
+	}
+	msg.RetryAt = m.retryAt
+	if m.rejoin == nil {
+		model, cmd := m.leaveRoom(msg)
+		return model, tea.Batch(cmd, dial)
+	}
+	var cmd tea.Cmd
+	m.home, cmd = m.home.Update(msg)
+	return m, tea.Batch(cmd, dial, m.waitForWSMessage())
+}
+
+// currentRoom describes the room on screen, if there is one
+func (m Model) currentRoom() *rejoin {
+	if m.screen != types.LobbyScreen && m.screen != types.RaceScreen && m.screen != types.ResultsScreen {
+		return nil
+	}
+	lobbyModel, ok := m.lobby.(screens.LobbyModel)
+	if !ok || lobbyModel.GetJoinCode() == "" {
+		return nil
+	}
+	return &rejoin{
+		token:      m.sessionToken,
+		code:       lobbyModel.GetJoinCode(),
+		password:   m.password,
+		spectating: lobbyModel.IsSpectating(),
+	}
+}
+
+// resume returns to the room the client was in before reconnecting,
+// first by resuming its old session
+func (m Model) resume() {
+	if m.rejoin.token != "" {
+		m.sendWSMessage(protocol.ResumeRequest{Token: m.rejoin.token})
+		return
+	}
+	m.rejoinRoom()
+}
+
+// rejoinRoom enters the room afresh, once the server no longer
+// holds the client's place in it
+func (m Model) rejoinRoom() {
+	if m.rejoin.spectating {
+		m.sendWSMessage(protocol.SpectateRoomRequest{Code: m.rejoin.code, Password: m.rejoin.password})
+		return
+	}
+	m.sendWSMessage(protocol.JoinRoomRequest{Code: m.rejoin.code, Name: m.config.Nickname, Password: m.rejoin.password})
+}
+
+// rejoinFailed gives up on the room the client was in
+func (m Model) rejoinFailed(reason string) (tea.Model, tea.Cmd) {
+	code := m.rejoin.code
+	m.rejoin = nil
+	return m.leaveRoom(types.ServerErrorMsg{Message: fmt.Sprintf("Could not rejoin room %s: %s", code, reason)})
+}
+
+// connectionNote describes an interrupted connection over the
+// current screen, or is empty if there is nothing to say
+func (m Model) connectionNote() string {
+	switch {
+	case m.connectionStatus == types.Connecting && m.rejoin != nil:
+		return "Reconnecting to server...\n" + m.spinner.View()
+	case m.connectionStatus == types.Connecting:
+		return "Connecting to server...\n" + m.spinner.View()
+	case m.rejoin != nil && m.connectionStatus != types.Connected:
+		note := "Connection lost."
+		if left := time.Until(m.retryAt); left > 0 {
+			note += fmt.Sprintf(" Retrying in %ds...", int(left.Round(time.Second)/time.Second))
+		}
+		return note + "\n" + m.spinner.View()
+	}
+	return ""
+}
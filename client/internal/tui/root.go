@@ -3,9 +3,11 @@
 package root
 
 import (
-	"encoding/json"
 	"net"
+	"net/url"
+	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -14,56 +16,95 @@ import (
 	"github.com/gorilla/websocket"
 	zone "github.com/lrstanley/bubblezone"
 
-	"github.com/givensuman/teletyperacer/client/internal/tui/components/input"
+	"github.com/givensuman/teletyperacer/client/internal/config"
+	"github.com/givensuman/teletyperacer/client/internal/tui/components/typing"
 	"github.com/givensuman/teletyperacer/client/internal/tui/screens"
 	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
 )
 
-// WebSocket message types
-type WSMessage struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data,omitempty"`
-}
-
-type CreateRoomData struct {
-	Code string `json:"code"`
-}
-
-type JoinRoomData struct {
-	Code string `json:"code"`
-}
-
-type RoomStateData struct {
-	Code        string `json:"code"`
-	PlayerCount int    `json:"playerCount"`
-	YourIndex   int    `json:"yourIndex"`
-	Version     int    `json:"version"`
-}
-
-type PlayerJoinedData struct {
-	PlayerIndex int `json:"playerIndex"`
-}
-
 type Model struct {
 	// Currently rendered screen
 	screen types.Screen
 	// Child models
 	home,
 	lobby,
-	practice tea.Model
+	practice,
+	race,
+	results tea.Model
 	// Join screen
 	join tea.Model
+	// Nickname screen
+	nickname tea.Model
+	// Password prompt for hosting a room
+	host tea.Model
+	// Server profile picker
+	servers tea.Model
+	// Settings remembered between runs
+	config config.Config
 	// WebSocket connection
 	conn    *websocket.Conn
 	spinner spinner.Model
+	// Closed when the connection is dropped on purpose
+	done chan struct{}
+	// URL of the server played on, and the profile it came from
+	server, profile string
+	// Counts dials, so the outcome of an abandoned one is ignored
+	gen int
+	// Failed dials since the last connection, and when the next is due
+	attempt int
+	retryAt time.Time
+	// Room to return to after reconnecting, if any
+	rejoin *rejoin
+	// Password given for the room last entered
+	password string
 	// Window dimensions
 	width, height int
 	// Connection status
 	connectionStatus types.ConnectionStatus
 	// WebSocket message channel
 	wsChan chan tea.Msg
+	// Race phase of the current room
+	phase types.RacePhase
+	// When typing progress was last sent to the server
+	lastProgressSent time.Time
+	// Resumable session issued by the server
+	sessionToken string
+	// Features the server enabled during the handshake
+	capabilities map[string]bool
+	// Passage languages the server offers
+	languages []string
+}
+
+// clientCapabilities lists the optional features this client supports
+var clientCapabilities = []string{
+	protocol.CapabilityHeartbeat,
+	protocol.CapabilityProgress,
+	protocol.CapabilityResume,
 }
 
+// buildVersion reports the module version the client was built
+// from, or "dev" for local builds
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// progressInterval throttles how often typing progress is sent
+// to the server during a race
+const progressInterval = 100 * time.Millisecond
+
+const (
+	// heartbeatTimeout is how long the client waits to hear anything
+	// from the server, pings included, before treating the connection
+	// as lost. The server pings well within this window.
+	heartbeatTimeout = 60 * time.Second
+	// writeTimeout bounds how long a single write to the server may take
+	writeTimeout = 10 * time.Second
+)
+
 type backgroundModel struct {
 	root *Model
 }
@@ -83,17 +124,27 @@ func (b backgroundModel) View() string {
 		content = b.root.practice.View()
 	case types.JoinScreen:
 		content = b.root.join.View()
+	case types.NicknameScreen:
+		content = b.root.nickname.View()
+	case types.HostScreen:
+		content = b.root.host.View()
+	case types.ServersScreen:
+		content = b.root.servers.View()
+	case types.RaceScreen:
+		content = b.root.race.View()
+	case types.ResultsScreen:
+		content = b.root.results.View()
 	default:
 		content = b.root.home.View()
 	}
 
-	if b.root.connectionStatus == types.Connecting {
+	if note := b.root.connectionNote(); note != "" {
 		spinnerView := lipgloss.NewStyle().
 			AlignVertical(lipgloss.Center).
 			AlignHorizontal(lipgloss.Center).
 			Width(b.root.width).
 			Height(b.root.height).
-			Render("Connecting to server...\n" + b.root.spinner.View())
+			Render(note)
 		return zone.Scan(lipgloss.NewStyle().
 			AlignVertical(lipgloss.Center).
 			AlignHorizontal(lipgloss.Center).
@@ -151,17 +202,17 @@ func categorizeConnectionError(err error) types.ConnectionStatus {
 }
 
 // sendWSMessage sends a message to the WebSocket server
-func (m Model) sendWSMessage(msgType string, data interface{}) {
+func (m Model) sendWSMessage(msg protocol.Message) {
 	if m.conn == nil {
 		return
 	}
 
-	msg := WSMessage{Type: msgType, Data: data}
-	jsonData, err := json.Marshal(msg)
+	data, err := protocol.Encode(msg)
 	if err != nil {
 		return
 	}
-	m.conn.WriteMessage(websocket.TextMessage, jsonData)
+	m.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	m.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 // copyToClipboard attempts to copy text to system clipboard
@@ -174,100 +225,210 @@ func (m Model) copyToClipboard(text string) tea.Cmd {
 	}
 }
 
-// handleWSMessageFromEvent processes incoming WebSocket messages from specific events
-func (m Model) handleWSMessageFromEvent(eventType string, data interface{}) tea.Msg {
-	switch eventType {
-	case "roomCreated":
-		var roomData CreateRoomData
-		if d, ok := data.(map[string]interface{}); ok {
-			if code, ok := d["code"].(string); ok {
-				roomData.Code = code
-			}
-		} else if d, ok := data.(CreateRoomData); ok {
-			roomData = d
+// handleServerMessage converts a message from the server into
+// the tea.Msg the TUI acts on
+func (m Model) handleServerMessage(msg protocol.Message) tea.Msg {
+	switch msg := msg.(type) {
+	case protocol.RoomCreatedResponse:
+		return types.RoomCreatedMsg{Code: msg.Code}
+
+	case protocol.RoomJoinedResponse:
+		return types.RoomJoinedMsg{Code: msg.Code}
+
+	case protocol.RoomListResponse:
+		listMsg := types.RoomListMsg{}
+		for _, r := range msg.Rooms {
+			listMsg.Rooms = append(listMsg.Rooms, types.RoomSummary{
+				Code:        r.Code,
+				Host:        r.Host,
+				PlayerCount: r.PlayerCount,
+				Spectators:  r.Spectators,
+				Phase:       parseRacePhase(r.Phase),
+				Settings:    settingsFrom(r.Settings),
+				Password:    r.Password,
+			})
 		}
-		return types.RoomCreatedMsg{Code: roomData.Code}
+		return listMsg
 
-	case "roomJoined":
-		var roomData JoinRoomData
-		if d, ok := data.(map[string]interface{}); ok {
-			if code, ok := d["code"].(string); ok {
-				roomData.Code = code
-			}
-		} else if d, ok := data.(JoinRoomData); ok {
-			roomData = d
+	case protocol.QueueStatusResponse:
+		return types.QueueStatusMsg{Waiting: msg.Waiting, MatchIn: msg.MatchIn}
+
+	case protocol.PlayerJoinedResponse:
+		return types.PlayerJoinedMsg{PlayerIndex: msg.PlayerIndex}
+
+	case protocol.RoomStateResponse:
+		stateMsg := types.RoomStateMsg{Code: msg.Code, PlayerCount: msg.PlayerCount, YourIndex: msg.YourIndex, Version: msg.Version, Phase: parseRacePhase(msg.Phase), Locked: msg.Locked, StartingIn: msg.StartingIn, Rounds: msg.Rounds, Spectators: msg.Spectators, Spectating: msg.Spectating}
+		stateMsg.Settings = settingsFrom(msg.Settings)
+		stateMsg.AutoStart = types.AutoStart{
+			WhenAllReady: msg.AutoStart.WhenAllReady,
+			MinPlayers:   msg.AutoStart.MinPlayers,
+			Delay:        msg.AutoStart.Delay,
 		}
-		return types.RoomJoinedMsg{Code: roomData.Code}
+		for _, p := range msg.Players {
+			stateMsg.Players = append(stateMsg.Players, types.Player{
+				Index:   p.Index,
+				Name:    p.Name,
+				Color:   p.Color,
+				Host:    p.Host,
+				Ready:   p.Ready,
+				Rematch: p.Rematch,
+				Points:  p.Points,
 
-	case "playerJoined":
-		var playerData PlayerJoinedData
-		if d, ok := data.(map[string]interface{}); ok {
-			if index, ok := d["playerIndex"].(float64); ok {
-				playerData.PlayerIndex = int(index)
-			}
-		} else if d, ok := data.(PlayerJoinedData); ok {
-			playerData = d
+				Rating:       p.Rating,
+				RatingChange: p.RatingChange,
+			})
 		}
-		return types.PlayerJoinedMsg{PlayerIndex: playerData.PlayerIndex}
+		return stateMsg
 
-	case "roomState":
-		var stateData RoomStateData
-		if d, ok := data.(map[string]interface{}); ok {
-			if code, ok := d["code"].(string); ok {
-				stateData.Code = code
-			}
-			if playerCount, ok := d["playerCount"].(float64); ok {
-				stateData.PlayerCount = int(playerCount)
-			}
-			if yourIndex, ok := d["yourIndex"].(float64); ok {
-				stateData.YourIndex = int(yourIndex)
-			}
-			if version, ok := d["version"].(float64); ok {
-				stateData.Version = int(version)
-			}
-		} else if d, ok := data.(RoomStateData); ok {
-			stateData = d
+	case protocol.RacePhaseResponse:
+		phaseMsg := types.RacePhaseMsg{Phase: parseRacePhase(msg.Phase), Countdown: msg.Countdown}
+		if msg.Passage != nil {
+			passage := passageFrom(*msg.Passage)
+			phaseMsg.Passage = &passage
+		}
+		return phaseMsg
+
+	case protocol.PlayerFinishedResponse:
+		return types.PlayerFinishedMsg{PlayerIndex: msg.PlayerIndex, Place: msg.Place}
+
+	case protocol.PlayerProgressResponse:
+		return types.PlayerProgressMsg{PlayerIndex: msg.PlayerIndex, Position: msg.Position, WPM: msg.WPM, Errors: msg.Errors}
+
+	case protocol.RaceResultsResponse:
+		resultsMsg := types.RaceResultsMsg{Passage: passageFrom(msg.Passage)}
+		for _, r := range msg.Results {
+			resultsMsg.Results = append(resultsMsg.Results, types.RaceResult{
+				PlayerIndex: r.PlayerIndex,
+				Name:        r.Name,
+				Color:       r.Color,
+				Rank:        r.Rank,
+				WPM:         r.WPM,
+				Accuracy:    r.Accuracy,
+				Time:        time.Duration(r.TimeMs) * time.Millisecond,
+				Finished:    r.Finished,
+				Points:      r.Points,
+				TotalPoints: r.TotalPoints,
+
+				Rating:       r.Rating,
+				RatingChange: r.RatingChange,
+			})
+		}
+		return resultsMsg
+
+	case protocol.WelcomeResponse:
+		return types.WelcomeMsg{
+			ProtocolVersion:    msg.ProtocolVersion,
+			MinProtocolVersion: msg.MinProtocolVersion,
+			ServerVersion:      msg.Version,
+			Capabilities:       msg.Capabilities,
+			Languages:          msg.Languages,
+		}
+
+	case protocol.SessionResponse:
+		return types.SessionMsg{ClientID: msg.ClientID, Token: msg.Token}
+
+	case protocol.SpectatingResponse:
+		spectatingMsg := types.SpectatingMsg{Code: msg.Code, Phase: parseRacePhase(msg.Phase)}
+		if msg.Passage != nil {
+			passage := passageFrom(*msg.Passage)
+			spectatingMsg.Passage = &passage
+		}
+		return spectatingMsg
+
+	case protocol.ResumedResponse:
+		resumedMsg := types.SessionResumedMsg{
+			Code:        msg.Code,
+			PlayerIndex: msg.PlayerIndex,
+			Phase:       parseRacePhase(msg.Phase),
+			Position:    msg.Position,
+			Finished:    msg.Finished,
 		}
-		return types.RoomStateMsg{Code: stateData.Code, PlayerCount: stateData.PlayerCount, YourIndex: stateData.YourIndex, Version: stateData.Version}
+		if msg.Passage != nil {
+			passage := passageFrom(*msg.Passage)
+			resumedMsg.Passage = &passage
+		}
+		return resumedMsg
+
+	case protocol.ChatResponse:
+		return types.ChatReceivedMsg{Message: chatMessageFrom(msg.Message)}
+
+	case protocol.ChatHistoryResponse:
+		historyMsg := types.ChatHistoryMsg{}
+		for _, message := range msg.Messages {
+			historyMsg.Messages = append(historyMsg.Messages, chatMessageFrom(message))
+		}
+		return historyMsg
+
+	case protocol.RoomClosedResponse:
+		return types.RoomClosedMsg{Code: msg.Code, Reason: msg.Reason}
 
-	case "error":
+	case protocol.ErrorResponse:
 		// Handle specific error types
-		if d, ok := data.(map[string]interface{}); ok {
-			if reason, ok := d["reason"].(string); ok {
-				return types.RoomJoinFailedMsg{Reason: reason}
-			}
+		if msg.Reason == protocol.ReasonUpdateRequired {
+			return types.ConnectionStatusMsg{Status: types.UpdateRequired}
+		}
+		if msg.Reason == protocol.ReasonSessionExpired {
+			return types.SessionExpiredMsg{}
+		}
+		if joinFailureReasons[msg.Reason] {
+			return types.RoomJoinFailedMsg{Reason: msg.Reason, Message: msg.Message}
+		}
+		if msg.Message != "" {
+			return types.ServerErrorMsg{Message: msg.Message}
 		}
-		return nil
 	}
 
 	return nil
 }
 
-// handleWSMessage processes incoming WebSocket messages (legacy, kept for compatibility)
-func (m Model) handleWSMessage(data interface{}) tea.Msg {
-	var wsMsg WSMessage
-	var jsonData []byte
-
-	// Handle different data types from WebSocket
-	switch d := data.(type) {
-	case string:
-		jsonData = []byte(d)
-	case []byte:
-		jsonData = d
-	default:
-		// Try to marshal the interface{} to JSON
-		if marshaled, err := json.Marshal(d); err == nil {
-			jsonData = marshaled
-		} else {
-			return nil
-		}
+// passageFrom converts a passage sent by the server
+func passageFrom(p protocol.PassageResponse) types.Passage {
+	return types.Passage{ID: p.ID, Text: p.Text, Source: p.Source, Author: p.Author}
+}
+
+func settingsFrom(s protocol.RoomSettings) types.RoomSettings {
+	return types.RoomSettings{
+		MaxPlayers:  s.MaxPlayers,
+		Length:      s.Length,
+		Language:    s.Language,
+		Punctuation: s.Punctuation,
+		Numbers:     s.Numbers,
+		Countdown:   s.Countdown,
+		Public:      s.Public,
 	}
+}
 
-	if err := json.Unmarshal(jsonData, &wsMsg); err != nil {
-		return nil
+func chatMessageFrom(c protocol.ChatMessage) types.ChatMessage {
+	return types.ChatMessage{
+		PlayerIndex: c.PlayerIndex,
+		Name:        c.Name,
+		Color:       c.Color,
+		Text:        c.Text,
+		SentAt:      time.UnixMilli(c.SentAt),
 	}
+}
+
+// joinFailureReasons are the error reasons that mean a join was refused
+var joinFailureReasons = map[string]bool{
+	protocol.ReasonRoomNotFound:   true,
+	protocol.ReasonRoomFull:       true,
+	protocol.ReasonRoomLocked:     true,
+	protocol.ReasonRaceInProgress: true,
+	protocol.ReasonWrongPassword:  true,
+}
 
-	return m.handleWSMessageFromEvent(wsMsg.Type, wsMsg.Data)
+// parseRacePhase converts the server's phase name to a types.RacePhase
+func parseRacePhase(phase string) types.RacePhase {
+	switch phase {
+	case protocol.PhaseCountdown:
+		return types.PhaseCountdown
+	case protocol.PhaseRacing:
+		return types.PhaseRacing
+	case protocol.PhaseFinished:
+		return types.PhaseFinished
+	default:
+		return types.PhaseWaiting
+	}
 }
 
 // waitForWSMessage waits for WebSocket messages
@@ -278,67 +439,165 @@ func (m Model) waitForWSMessage() tea.Cmd {
 	}
 }
 
-func New() Model {
-	conn, _, err := websocket.DefaultDialer.Dial("ws://localhost:3000/ws/", nil)
+// Options are settings for a single run. They take precedence over
+// the config file and are never saved.
+type Options struct {
+	Server   string // websocket URL of the server to play on
+	Profile  string // profile Server belongs to, empty for none
+	Nickname string
+}
 
+func New(cfg config.Config, opts Options) Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
-	connectionStatus := types.Connected
-	if err != nil {
-		connectionStatus = categorizeConnectionError(err)
+	if opts.Nickname != "" {
+		cfg.Nickname = opts.Nickname
+	}
+	home, _ := screens.NewHome().Update(types.NicknameChangedMsg{Name: cfg.Nickname})
+
+	m := Model{
+		screen:   types.HomeScreen,
+		home:     home,
+		lobby:    screens.NewHostLobby(nil, ""),
+		practice: screens.NewPractice(),
+		race:     screens.NewRace(types.Passage{Text: screens.SampleText}, nil, 0),
+		results:  screens.NewResults(types.RaceResultsMsg{}, nil, 0, false),
+		join:     screens.NewJoin(),
+		nickname: screens.NewNickname(cfg.Nickname),
+		host:     screens.NewHost(),
+		servers:  screens.NewServers(nil, "", ""),
+		config:   cfg,
+		spinner:  s,
+		width:    80,
+		height:   24,
+		wsChan:   make(chan tea.Msg, 10),
 	}
+	return m.useServer(opts.Server, opts.Profile)
+}
+
+// useServer sets the server to play on. The caller dials it.
+func (m Model) useServer(server, profile string) Model {
+	m.server, m.profile = server, profile
+	m.home, _ = m.home.Update(types.ServerChangedMsg{Name: serverName(server, profile)})
+	m.connectionStatus = types.Connecting
+	m.attempt = 0
+	return m
+}
 
-	return Model{
-		screen:           types.HomeScreen,
-		home:             screens.NewHome(),
-		lobby:            screens.NewHostLobby(),
-		practice:         screens.NewPractice(),
-		join:             screens.NewJoin(),
-		conn:             conn,
-		spinner:          s,
-		width:            80,
-		height:           24,
-		connectionStatus: connectionStatus,
-		wsChan:           make(chan tea.Msg, 10),
+// disconnect drops the connection without reporting it as lost
+func (m Model) disconnect() Model {
+	if m.conn != nil {
+		close(m.done)
+		m.conn.Close()
+		m.conn = nil
 	}
+	m.sessionToken = ""
+	m.capabilities = nil
+	m.languages = nil
+	m.phase = types.PhaseWaiting
+	return m
+}
+
+// serverList lists the default server and every profile
+func (m Model) serverList() screens.ServersModel {
+	def, _ := m.config.ServerFor("")
+	profiles := []types.ServerProfile{{Server: def}}
+	for _, p := range m.config.Profiles {
+		profiles = append(profiles, types.ServerProfile{Name: p.Name, Server: p.Server})
+	}
+	path, _ := config.Path()
+	return screens.NewServers(profiles, m.profile, path)
+}
+
+// serverName is how the home screen refers to a server
+func serverName(server, profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return server
 }
 
 func (m Model) Init() tea.Cmd {
+	return tea.Batch(
+		m.spinner.Tick,
+		dialServer(m.server, m.gen),
+		m.waitForWSMessage(),
+	)
+}
+
+// connect introduces the client over a freshly dialed connection
+// and starts reading from it
+func (m Model) connect() {
 	// Start WebSocket message reader
 	if m.conn != nil {
+		// Introduce ourselves; the server answers with welcome,
+		// or with an error if this client is too old
+		m.sendWSMessage(protocol.HelloRequest{
+			ProtocolVersion: protocol.Version,
+			Version:         buildVersion(),
+			Capabilities:    clientCapabilities,
+			PlayerID:        m.config.PlayerID,
+		})
+
+		// Every ping from the server proves it is still there
+		m.conn.SetReadDeadline(time.Now().Add(heartbeatTimeout))
+		m.conn.SetPingHandler(func(appData string) error {
+			m.conn.SetReadDeadline(time.Now().Add(heartbeatTimeout))
+			err := m.conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(writeTimeout))
+			if err == websocket.ErrCloseSent {
+				return nil
+			}
+			return err
+		})
+
 		go func() {
 			for {
 				_, data, err := m.conn.ReadMessage()
 				if err != nil {
-					// Connection closed or error
+					select {
+					case <-m.done:
+						// Dropped on purpose, to switch servers
+					default:
+						// Connection closed, or the server went quiet for too long
+						m.wsChan <- types.ConnectionStatusMsg{Status: types.Disconnected}
+					}
 					return
 				}
+				m.conn.SetReadDeadline(time.Now().Add(heartbeatTimeout))
 
-				var wsMsg WSMessage
-				if err := json.Unmarshal(data, &wsMsg); err != nil {
+				serverMsg, err := protocol.Decode(data)
+				if err != nil {
 					continue
 				}
 
-				if msg := m.handleWSMessageFromEvent(wsMsg.Type, wsMsg.Data); msg != nil {
+				msg := m.handleServerMessage(serverMsg)
+				if msg == nil {
+					continue
+				}
+				if _, progress := msg.(types.PlayerProgressMsg); progress {
+					// The next update supersedes this one, so progress
+					// is dropped rather than held up behind a busy screen
 					select {
 					case m.wsChan <- msg:
 					default:
-						// Channel full, drop message
 					}
+					continue
+				}
+				// Everything else must arrive, or the screens fall out
+				// of step with the room
+				select {
+				case m.wsChan <- msg:
+				case <-m.done:
+					return
 				}
 			}
 		}()
 	}
-
-	return tea.Batch(
-		m.spinner.Tick,
-		func() tea.Msg {
-			return types.ConnectionStatusMsg{Status: m.connectionStatus}
-		},
-		m.waitForWSMessage(),
-	)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -350,16 +609,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateCurrentScreen(msg)
 
 	case types.ScreenChangeMsg:
+		prev := m.screen
 		m.screen = msg.Screen
 		if msg.Screen == types.JoinScreen {
 			m.join = screens.NewJoin()
 			return m, m.join.Init()
 		}
+		if msg.Screen == types.NicknameScreen {
+			m.nickname = screens.NewNickname(m.config.Nickname)
+			return m, m.nickname.Init()
+		}
+		if msg.Screen == types.ServersScreen {
+			m.servers = m.serverList()
+			return m, m.servers.Init()
+		}
+		if msg.Screen == types.HostScreen {
+			m.host = screens.NewHost()
+			return m, m.host.Init()
+		}
 		if msg.Screen == types.LobbyScreen {
-			if m.screen == types.HomeScreen {
-				m.lobby = screens.NewHostLobby()
+			if prev == types.JoinScreen || prev == types.HomeScreen {
+				// Joined from the join screen or a quick match
+				return m, m.lobby.Init()
 			}
-			return m, m.lobby.Init()
+			// Returning from a race, the lobby is already set up
+			return m, nil
 		}
 		return m, nil
 
@@ -369,33 +643,292 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case types.ConnectionStatusMsg:
+		if msg.Status == types.Disconnected && m.connectionStatus == types.UpdateRequired {
+			// The server hangs up on clients it has told to update
+			return m, nil
+		}
 		m.connectionStatus = msg.Status
+		if msg.Status == types.Disconnected {
+			return m.connectionLost(msg)
+		}
+		if msg.Status == types.UpdateRequired {
+			return m.leaveRoom(msg)
+		}
+		if m.screen != types.HomeScreen {
+			// Home was left behind while reconnecting to a room
+			m.home, _ = m.home.Update(msg)
+		}
 		// Forward connection status to current screen
 		return m.updateCurrentScreen(msg)
 
-	case types.CreateRoomMsg:
-		// Get the join code from the lobby screen
-		if lobbyModel, ok := m.lobby.(screens.LobbyModel); ok {
-			m.sendWSMessage("createRoom", CreateRoomData{Code: lobbyModel.GetJoinCode()})
+	case types.RoomClosedMsg:
+		return m.leaveRoom(msg)
+
+	case types.SwitchServerMsg:
+		m.screen = types.HomeScreen
+		server, err := m.config.ServerFor(msg.Profile)
+		if err != nil {
+			m.home, _ = m.home.Update(types.ServerErrorMsg{Message: err.Error()})
+			return m, nil
+		}
+		if err := config.Update(func(c *config.Config) { c.Profile = msg.Profile }); err != nil {
+			// The switch still applies for this session
+			m.home, _ = m.home.Update(types.ServerErrorMsg{Message: "Could not save server choice: " + err.Error()})
+		}
+		m.config.Profile = msg.Profile
+		m.rejoin = nil
+		m, dial := m.disconnect().useServer(server, msg.Profile).redial()
+		var cmd tea.Cmd
+		m.home, cmd = m.home.Update(types.ConnectionStatusMsg{Status: types.Connecting})
+		return m, tea.Batch(cmd, dial)
+
+	case dialedMsg:
+		return m.dialed(msg)
+
+	case redialMsg:
+		if msg.gen != m.gen {
+			return m, nil
 		}
+		m, dial := m.redial()
+		m.connectionStatus = types.Connecting
+		var cmd tea.Cmd
+		m.home, cmd = m.home.Update(types.ConnectionStatusMsg{Status: types.Connecting})
+		return m, tea.Batch(cmd, dial)
+
+	case types.HostRoomMsg:
+		m.lobby = screens.NewHostLobby(m.languages, msg.Password)
+		m.screen = types.LobbyScreen
+		return m, m.lobby.Init()
+
+	case types.CreateRoomMsg:
+		// The server generates the room code
+		m.password = msg.Password
+		m.sendWSMessage(protocol.CreateRoomRequest{Name: m.config.Nickname, Password: msg.Password})
 		return m, nil
 
 	case types.JoinRoomMsg:
-		m.sendWSMessage("joinRoom", JoinRoomData{Code: msg.Code})
+		// A spectator taking a seat joins the room it is watching
+		// without a password, but a rejoin may still need the one
+		// it was let in with
+		if room := m.currentRoom(); room == nil || room.code != msg.Code {
+			m.password = msg.Password
+		}
+		m.sendWSMessage(protocol.JoinRoomRequest{Code: msg.Code, Name: m.config.Nickname, Password: msg.Password})
+		return m, nil
+
+	case types.ListRoomsMsg:
+		m.sendWSMessage(protocol.ListRoomsRequest{})
+		return m, nil
+
+	case types.SpectateRoomMsg:
+		m.password = msg.Password
+		m.sendWSMessage(protocol.SpectateRoomRequest{Code: msg.Code, Password: msg.Password})
+		return m, nil
+
+	case types.SpectatingMsg:
+		// Joining mid-race lands here too, straight onto the track
+		m.rejoin = nil
+		m.phase = msg.Phase
+		m.lobby = screens.NewSpectatorLobby(msg.Code, m.languages)
+		m.screen = types.LobbyScreen
+		if msg.Passage != nil && (msg.Phase == types.PhaseCountdown || msg.Phase == types.PhaseRacing) {
+			race := screens.NewSpectatorRace(*msg.Passage, nil, msg.Phase)
+			m.race, _ = race.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+			m.screen = types.RaceScreen
+		}
+		return m, tea.Batch(m.waitForWSMessage(), func() tea.Msg { return types.GetRoomStateMsg{Code: msg.Code} })
+
+	case types.QuickMatchMsg:
+		m.sendWSMessage(protocol.QuickMatchRequest{Name: m.config.Nickname, Ranked: msg.Ranked})
+		m.home, _ = m.home.Update(msg)
+		return m, nil
+
+	case types.LeaveRoomMsg:
+		m.rejoin = nil
+		m.sendWSMessage(protocol.LeaveRoomRequest{})
+		m.phase = types.PhaseWaiting
 		return m, nil
 
 	case types.GetRoomStateMsg:
-		m.sendWSMessage("getRoomState", map[string]string{"code": msg.Code})
+		m.sendWSMessage(protocol.GetRoomStateRequest{Code: msg.Code})
+		return m, nil
+
+	case types.StartRaceMsg:
+		m.sendWSMessage(protocol.StartRaceRequest{})
+		return m, nil
+
+	case types.FinishRaceMsg:
+		m.sendWSMessage(protocol.FinishRaceRequest{})
+		return m, nil
+
+	case types.SendChatMsg:
+		m.sendWSMessage(protocol.ChatRequest{Text: msg.Text})
+		return m, nil
+
+	case types.ChatReceivedMsg, types.ChatHistoryMsg:
+		return m.updateRoomScreens(msg)
+
+	case types.SetSettingsMsg:
+		m.sendWSMessage(protocol.SetSettingsRequest{Settings: protocol.RoomSettings{
+			MaxPlayers:  msg.Settings.MaxPlayers,
+			Length:      msg.Settings.Length,
+			Language:    msg.Settings.Language,
+			Punctuation: msg.Settings.Punctuation,
+			Numbers:     msg.Settings.Numbers,
+			Countdown:   msg.Settings.Countdown,
+			Public:      msg.Settings.Public,
+		}})
+		return m, nil
+
+	case types.SetReadyMsg:
+		m.sendWSMessage(protocol.SetReadyRequest{Ready: msg.Ready})
+		return m, nil
+
+	case types.SetAutoStartMsg:
+		m.sendWSMessage(protocol.SetAutoStartRequest{AutoStart: protocol.AutoStart{
+			WhenAllReady: msg.AutoStart.WhenAllReady,
+			MinPlayers:   msg.AutoStart.MinPlayers,
+			Delay:        msg.AutoStart.Delay,
+		}})
 		return m, nil
 
-	case input.SubmitMsg:
-		// Send join room message to server
-		return m, func() tea.Msg {
-			return types.JoinRoomMsg{Code: strings.ToUpper(msg.Value)}
+	case types.KickPlayerMsg:
+		m.sendWSMessage(protocol.KickPlayerRequest{PlayerIndex: msg.PlayerIndex})
+		return m, nil
+
+	case types.LockRoomMsg:
+		m.sendWSMessage(protocol.LockRoomRequest{Locked: msg.Locked})
+		return m, nil
+
+	case types.TransferHostMsg:
+		m.sendWSMessage(protocol.TransferHostRequest{PlayerIndex: msg.PlayerIndex})
+		return m, nil
+
+	case types.VoteRematchMsg:
+		m.sendWSMessage(protocol.VoteRematchRequest{Vote: msg.Vote})
+		return m, nil
+
+	case types.AcceptRematchMsg:
+		m.sendWSMessage(protocol.AcceptRematchRequest{})
+		return m, nil
+
+	case types.RoomStateMsg:
+		m.phase = msg.Phase
+		return m.updateRoomScreens(msg)
+
+	case types.RacePhaseMsg:
+		m.phase = msg.Phase
+		if msg.Phase == types.PhaseCountdown && (m.screen == types.LobbyScreen || m.screen == types.ResultsScreen) {
+			// Move from the lobby onto the track
+			var roster []types.Player
+			playerIndex, spectating := 0, false
+			if lobbyModel, ok := m.lobby.(screens.LobbyModel); ok {
+				roster = lobbyModel.GetRoster()
+				playerIndex = lobbyModel.GetPlayerIndex()
+				spectating = lobbyModel.IsSpectating()
+			}
+			passage := types.Passage{Text: screens.SampleText}
+			if msg.Passage != nil {
+				passage = *msg.Passage
+			}
+			if spectating {
+				m.race = screens.NewSpectatorRace(passage, roster, types.PhaseWaiting)
+			} else {
+				m.race = screens.NewRace(passage, roster, playerIndex)
+			}
+			m.race, _ = m.race.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+			m.screen = types.RaceScreen
+			return m.updateRoomScreens(msg)
+		}
+		if msg.Phase == types.PhaseWaiting && m.screen == types.ResultsScreen {
+			// The host accepted a rematch
+			m.screen = types.LobbyScreen
+		}
+		return m.updateRoomScreens(msg)
+
+	case types.WelcomeMsg:
+		if msg.MinProtocolVersion > protocol.Version {
+			return m.Update(types.ConnectionStatusMsg{Status: types.UpdateRequired})
+		}
+		m.languages = msg.Languages
+		m.capabilities = make(map[string]bool, len(msg.Capabilities))
+		for _, capability := range msg.Capabilities {
+			m.capabilities[capability] = true
 		}
+		m.attempt = 0
+		m.retryAt = time.Time{}
+		if m.rejoin != nil {
+			m.resume()
+		}
+		return m.Update(types.ConnectionStatusMsg{Status: types.Connected})
 
-	case input.HideMsg:
-		return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} }
+	case types.SessionMsg:
+		m.sessionToken = msg.Token
+		return m, m.waitForWSMessage()
+
+	case types.SessionResumedMsg:
+		if m.rejoin != nil {
+			// The server carries on with the old session
+			m.sessionToken = m.rejoin.token
+			if msg.Code == "" {
+				// Our place in the room was given up
+				m.rejoinRoom()
+				return m, m.waitForWSMessage()
+			}
+			m.rejoin = nil
+		}
+		if msg.Code == "" {
+			return m, m.waitForWSMessage()
+		}
+		m.phase = msg.Phase
+		if lobbyModel, ok := m.lobby.(screens.LobbyModel); !ok || lobbyModel.GetJoinCode() != msg.Code {
+			m.lobby = screens.NewPlayerLobby(msg.Code, m.languages)
+		}
+		if msg.Passage != nil && !msg.Finished && (msg.Phase == types.PhaseCountdown || msg.Phase == types.PhaseRacing) {
+			var roster []types.Player
+			if lobbyModel, ok := m.lobby.(screens.LobbyModel); ok {
+				roster = lobbyModel.GetRoster()
+			}
+			race := screens.NewRace(*msg.Passage, roster, msg.PlayerIndex).Resume(msg.Phase, msg.Position)
+			m.race, _ = race.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+			m.screen = types.RaceScreen
+		} else {
+			m.screen = types.LobbyScreen
+		}
+		return m, tea.Batch(m.waitForWSMessage(), func() tea.Msg { return types.GetRoomStateMsg{Code: msg.Code} })
+
+	case types.RaceResultsMsg:
+		var roster []types.Player
+		playerIndex, isHost := 0, false
+		if lobbyModel, ok := m.lobby.(screens.LobbyModel); ok {
+			roster = lobbyModel.GetRoster()
+			playerIndex = lobbyModel.GetPlayerIndex()
+			isHost = lobbyModel.IsHost()
+		}
+		m.results = screens.NewResults(msg, roster, playerIndex, isHost)
+		m.screen = types.ResultsScreen
+		return m, tea.Batch(m.results.Init(), m.waitForWSMessage())
+
+	case typing.ProgressMsg:
+		// Stream progress to the server while racing, always
+		// letting the final update through
+		if m.phase == types.PhaseRacing && m.screen == types.RaceScreen && m.capabilities[protocol.CapabilityProgress] {
+			if msg.Progress >= 100 || time.Since(m.lastProgressSent) >= progressInterval {
+				m.sendWSMessage(protocol.ProgressRequest{Position: msg.Position, WPM: msg.WPM, Errors: msg.Errors})
+				m.lastProgressSent = time.Now()
+			}
+		}
+		return m.updateCurrentScreen(msg)
+
+	case types.NicknameChangedMsg:
+		m.config.Nickname = msg.Name
+		if err := config.Update(func(c *config.Config) { c.Nickname = msg.Name }); err != nil {
+			// The nickname still applies for this session
+			m.home, _ = m.home.Update(types.ServerErrorMsg{Message: "Could not save nickname: " + err.Error()})
+		}
+		m.home, _ = m.home.Update(msg)
+		m.screen = types.HomeScreen
+		return m, m.waitForWSMessage()
 
 	case types.CopyCodeMsg:
 		// Try to copy code to clipboard using common commands
@@ -410,12 +943,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Could show an error notification here
 		return m, nil
 
+	case types.SessionExpiredMsg:
+		if m.rejoin != nil {
+			m.rejoinRoom()
+		}
+		return m, m.waitForWSMessage()
+
 	case types.RoomJoinedMsg:
-		// Successfully joined room, switch to player lobby
-		m.lobby = screens.NewPlayerLobby(msg.Code)
+		// Successfully joined room, switch to player lobby. Quick
+		// matches are made from home, which stops searching.
+		m.rejoin = nil
+		m.home, _ = m.home.Update(msg)
+		m.lobby = screens.NewPlayerLobby(msg.Code, m.languages)
 		return m, tea.Batch(func() tea.Msg { return types.ScreenChangeMsg{Screen: types.LobbyScreen} }, func() tea.Msg { return types.GetRoomStateMsg{} })
 
 	case types.RoomJoinFailedMsg:
+		if m.rejoin != nil {
+			return m.rejoinFailed(msg.Message)
+		}
 		// Forward to current screen to handle the error
 		return m.updateCurrentScreen(msg)
 
@@ -425,6 +970,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// updateRoomScreens keeps the lobby in sync with room-level messages
+// while a race is on screen, then forwards msg to the current screen
+func (m Model) updateRoomScreens(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.screen == types.RaceScreen || m.screen == types.ResultsScreen {
+		// The lobby is in the background, so its commands are discarded
+		m.lobby, _ = m.lobby.Update(msg)
+	}
+	return m.updateCurrentScreen(msg)
+}
+
+// leaveRoom drops back to the home screen after losing the current
+// room, letting the home screen explain why. Practice is left alone
+// since it does not need the server.
+func (m Model) leaveRoom(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.phase = types.PhaseWaiting
+	m.rejoin = nil
+	var cmd tea.Cmd
+	m.home, cmd = m.home.Update(msg)
+	if m.screen != types.PracticeScreen {
+		m.screen = types.HomeScreen
+	}
+	return m, tea.Batch(cmd, m.waitForWSMessage())
+}
+
 func (m Model) updateCurrentScreen(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch m.screen {
@@ -436,6 +1005,16 @@ func (m Model) updateCurrentScreen(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.practice, cmd = m.practice.Update(msg)
 	case types.JoinScreen:
 		m.join, cmd = m.join.Update(msg)
+	case types.NicknameScreen:
+		m.nickname, cmd = m.nickname.Update(msg)
+	case types.HostScreen:
+		m.host, cmd = m.host.Update(msg)
+	case types.ServersScreen:
+		m.servers, cmd = m.servers.Update(msg)
+	case types.RaceScreen:
+		m.race, cmd = m.race.Update(msg)
+	case types.ResultsScreen:
+		m.results, cmd = m.results.Update(msg)
 	default:
 		cmd = nil
 	}
@@ -455,17 +1034,27 @@ func (m Model) View() string {
 		content = m.practice.View()
 	case types.JoinScreen:
 		content = m.join.View()
+	case types.NicknameScreen:
+		content = m.nickname.View()
+	case types.HostScreen:
+		content = m.host.View()
+	case types.ServersScreen:
+		content = m.servers.View()
+	case types.RaceScreen:
+		content = m.race.View()
+	case types.ResultsScreen:
+		content = m.results.View()
 	default:
 		content = m.home.View()
 	}
 
-	if m.connectionStatus == types.Connecting {
+	if note := m.connectionNote(); note != "" {
 		spinnerView := lipgloss.NewStyle().
 			AlignVertical(lipgloss.Center).
 			AlignHorizontal(lipgloss.Center).
 			Width(m.width).
 			Height(m.height).
-			Render("Connecting to server...\n" + m.spinner.View())
+			Render(note)
 		content = lipgloss.NewStyle().
 			AlignVertical(lipgloss.Center).
 			AlignHorizontal(lipgloss.Center).
@@ -4,6 +4,8 @@ package screens
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -13,12 +15,29 @@ import (
 	"github.com/givensuman/teletyperacer/client/internal/types"
 )
 
+// onlineButtons is how many of the home buttons, from the top,
+// need a server connection. The first searchButtons of them look
+// for a match.
+const (
+	onlineButtons = 4
+	searchButtons = 2
+)
+
 type HomeModel struct {
 	cursor           int
-	choices          [4]button.Model
+	choices          [8]button.Model
 	notification     string
+	notice           string // why the player was last sent back here, if anything
+	nickname         string
+	server           string    // name of the server played on
+	retryAt          time.Time // when the client next tries to connect, zero if it won't
 	spinner          spinner.Model
 	connectionStatus types.ConnectionStatus
+
+	searching bool      // waiting in the quick-match queue
+	ranked    bool      // searching the ranked queue
+	waiting   int       // players in the queue, including this one
+	matchAt   time.Time // when the server makes a room for whoever is waiting
 }
 
 func NewHome() HomeModel {
@@ -26,27 +45,37 @@ func NewHome() HomeModel {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
-	joinBtn := button.NewFocusedButton("Join", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.JoinScreen} })
-	hostBtn := button.NewButton("Host", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.LobbyScreen} })
-
-	// Initially disable Join and Host since connection starts as Connecting
-	disabledJoinBtn, _ := joinBtn.Update(button.Disable)
-	disabledHostBtn, _ := hostBtn.Update(button.Disable)
-	joinBtn = disabledJoinBtn.(button.Model)
-	hostBtn = disabledHostBtn.(button.Model)
-
-	return HomeModel{
+	m := HomeModel{
 		cursor: 0,
-		choices: [4]button.Model{
-			joinBtn,
-			hostBtn,
+		choices: [8]button.Model{
+			button.NewFocusedButton("Quick Race", func() tea.Msg { return types.QuickMatchMsg{} }),
+			button.NewButton("Ranked Race", func() tea.Msg { return types.QuickMatchMsg{Ranked: true} }),
+			button.NewButton("Join", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.JoinScreen} }),
+			button.NewButton("Host", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HostScreen} }),
 			button.NewButton("Practice", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.PracticeScreen} }),
+			button.NewButton("Nickname", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.NicknameScreen} }),
+			button.NewButton("Server", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.ServersScreen} }),
 			button.NewButton("Quit", tea.Quit),
 		},
 		notification:     "",
 		spinner:          s,
 		connectionStatus: types.Connecting,
 	}
+	// Online play waits for the connection, which starts as Connecting
+	return m.setOnline(false)
+}
+
+// setOnline enables or disables the buttons that need the server
+func (m HomeModel) setOnline(online bool) HomeModel {
+	toggle := button.Disable
+	if online {
+		toggle = button.Enable
+	}
+	for i := range onlineButtons {
+		btn, _ := m.choices[i].Update(toggle)
+		m.choices[i] = btn.(button.Model)
+	}
+	return m
 }
 
 func (m HomeModel) Init() tea.Cmd {
@@ -92,52 +121,83 @@ func (m HomeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case types.ConnectionStatusMsg:
 		m.connectionStatus = msg.Status
+		m.retryAt = msg.RetryAt
+		if msg.Status != types.Connected {
+			m.searching = false
+		}
 		switch msg.Status {
 		case types.Connected:
 			m.notification = "Connected to server successfully."
-			// Enable Join and Host buttons
-			enabledJoin, _ := m.choices[0].Update(button.Enable)
-			enabledHost, _ := m.choices[1].Update(button.Enable)
-			m.choices[0] = enabledJoin.(button.Model)
-			m.choices[1] = enabledHost.(button.Model)
+			m = m.setOnline(true)
 		case types.Connecting:
 			m.notification = "Connecting to server..."
 		case types.ServerUnreachable:
-			m.notification = "Server unreachable. Join and Host are disabled."
-			// Disable Join and Host buttons
-			disabledJoin, _ := m.choices[0].Update(button.Disable)
-			disabledHost, _ := m.choices[1].Update(button.Disable)
-			m.choices[0] = disabledJoin.(button.Model)
-			m.choices[1] = disabledHost.(button.Model)
+			m.notification = "Server unreachable. Online play is disabled."
+			m = m.setOnline(false)
 		case types.ClientError:
-			m.notification = "Client configuration error. Join and Host are disabled."
-			// Disable Join and Host buttons
-			disabledJoin, _ := m.choices[0].Update(button.Disable)
-			disabledHost, _ := m.choices[1].Update(button.Disable)
-			m.choices[0] = disabledJoin.(button.Model)
-			m.choices[1] = disabledHost.(button.Model)
+			m.notification = "Client configuration error. Online play is disabled."
+			m = m.setOnline(false)
 		case types.Failed:
 			// Keep backward compatibility - treat as server unreachable
-			m.notification = "Connection failed. Join and Host are disabled."
-			// Disable Join and Host buttons
-			disabledJoin, _ := m.choices[0].Update(button.Disable)
-			disabledHost, _ := m.choices[1].Update(button.Disable)
-			m.choices[0] = disabledJoin.(button.Model)
-			m.choices[1] = disabledHost.(button.Model)
-
-			// If current cursor is on a disabled button, move to next enabled one
+			m.notification = "Connection failed. Online play is disabled."
+			m = m.setOnline(false)
+		case types.UpdateRequired:
+			m.notification = "This version of teletyperacer is out of date. Update it to play online."
+			m = m.setOnline(false)
+		case types.Disconnected:
+			m.notification = "Lost connection to server. Reconnecting..."
+			m = m.setOnline(false)
+		}
+
+		// If current cursor is on a disabled button, move to next enabled one
+		if m.choices[m.cursor].IsDisabled() {
+			prevBtn, _ := m.choices[m.cursor].Update(button.Unfocus)
+			m.choices[m.cursor] = prevBtn.(button.Model)
+			m.cursor = m.findNextEnabledButton(m.cursor, 1)
 			if m.choices[m.cursor].IsDisabled() {
-				m.cursor = m.findNextEnabledButton(m.cursor, 1)
-				if m.choices[m.cursor].IsDisabled() {
-					m.cursor = m.findNextEnabledButton(m.cursor, -1)
-				}
-				// Focus the new cursor position
-				btn, cmd := m.choices[m.cursor].Update(button.Focus)
-				m.choices[m.cursor] = btn.(button.Model)
-				cmds = append(cmds, cmd)
+				m.cursor = m.findNextEnabledButton(m.cursor, -1)
 			}
+			// Focus the new cursor position
+			btn, cmd := m.choices[m.cursor].Update(button.Focus)
+			m.choices[m.cursor] = btn.(button.Model)
+			cmds = append(cmds, cmd)
+		}
+
+	case types.NicknameChangedMsg:
+		m.nickname = msg.Name
+
+	case types.ServerChangedMsg:
+		m.server = msg.Name
+
+	case types.RoomClosedMsg:
+		switch msg.Reason {
+		case "idle":
+			m.notice = fmt.Sprintf("Room %s was closed after a period of inactivity.", msg.Code)
+		case "kicked":
+			m.notice = fmt.Sprintf("You were removed from room %s by the host.", msg.Code)
+		case "empty":
+			m.notice = fmt.Sprintf("Everyone left room %s.", msg.Code)
+		default:
+			m.notice = fmt.Sprintf("Room %s was closed.", msg.Code)
 		}
 
+	case types.ServerErrorMsg:
+		m.notice = msg.Message
+
+	case types.QuickMatchMsg:
+		m.searching = true
+		m.ranked = msg.Ranked
+		m.waiting = 1
+		m.matchAt = time.Time{}
+
+	case types.QueueStatusMsg:
+		m.searching = true
+		m.waiting = msg.Waiting
+		m.matchAt = time.Now().Add(time.Duration(msg.MatchIn) * time.Second)
+
+	case types.RoomJoinedMsg:
+		m.searching = false
+
 	case button.WidthMsg:
 		for i, btn := range m.choices {
 			updatedBtn, _ := btn.Update(msg)
@@ -166,7 +226,13 @@ func (m HomeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "enter":
 			if !m.choices[m.cursor].IsDisabled() {
-				return m, tea.Batch(m.choices[m.cursor].GetAction(), m.spinner.Tick)
+				return m.choose(m.cursor)
+			}
+
+		case "esc":
+			if m.searching {
+				m.searching = false
+				return m, func() tea.Msg { return types.LeaveRoomMsg{} }
 			}
 
 		case "q":
@@ -177,7 +243,7 @@ func (m HomeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
 			for i := range m.choices {
 				if zone.Get(fmt.Sprintf("button-%d", i)).InBounds(msg) && !m.choices[i].IsDisabled() {
-					return m, tea.Batch(m.choices[i].GetAction(), m.spinner.Tick)
+					return m.choose(i)
 				}
 			}
 		}
@@ -206,6 +272,18 @@ func (m HomeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// choose runs the action of the i-th button. Choosing anything but a
+// search gives up the search in progress.
+func (m HomeModel) choose(i int) (HomeModel, tea.Cmd) {
+	m.notice = ""
+	var cancel tea.Cmd
+	if m.searching && i >= searchButtons {
+		m.searching = false
+		cancel = func() tea.Msg { return types.LeaveRoomMsg{} }
+	}
+	return m, tea.Batch(cancel, m.choices[i].GetAction(), m.spinner.Tick)
+}
+
 func (m HomeModel) View() string {
 	var views []string
 	for i, btn := range m.choices {
@@ -260,12 +338,56 @@ func (m HomeModel) View() string {
 		status = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("1")).
 			Render("✗ Client configuration error")
+	case types.Disconnected:
+		status = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("1")).
+			Render("✗ Disconnected")
+	case types.UpdateRequired:
+		status = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("3")).
+			Render("⬆ Update required")
 	case types.Failed:
 		status = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("1")).
 			Render("✗ Connection failed")
 	}
 
+	if left := time.Until(m.retryAt); left > 0 && m.connectionStatus != types.Connected {
+		status += lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(fmt.Sprintf(" • retrying in %ds", int(left.Round(time.Second)/time.Second)))
+	}
+	if m.server != "" {
+		status += lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(" • " + m.server)
+	}
+	if m.nickname != "" {
+		status += lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(" • playing as " + m.nickname)
+	}
+
+	if m.searching {
+		kind := "race"
+		if m.ranked {
+			kind = "ranked race"
+		}
+		search := fmt.Sprintf("%s Searching for a %s • %d waiting", m.spinner.View(), kind, m.waiting)
+		if left := time.Until(m.matchAt); left > 0 {
+			search += fmt.Sprintf(" • matching in %ds", int(left.Round(time.Second)/time.Second))
+		}
+		status += "\n" + search + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(" (esc to cancel)")
+	}
+
+	if m.notice != "" {
+		status += "\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("3")).
+			Render(m.notice)
+	}
+
 	statusNotifier := lipgloss.NewStyle().
 		Padding(1, 0).
 		AlignHorizontal(lipgloss.Left).
@@ -0,0 +1,48 @@
+package screens
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/givensuman/teletyperacer/client/internal/tui/components/input"
+	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// HostModel asks for an optional password before hosting a room
+type HostModel struct {
+	input input.Model
+}
+
+func NewHost() HostModel {
+	return HostModel{
+		input: input.NewInput(input.Config{
+			Placeholder:    "Leave empty for an open room",
+			Label:          "Room Password",
+			SubmittedLabel: "Creating Room...",
+			SubmittedText:  "Setting up your room",
+			CharLimit:      protocol.MaxPasswordLength,
+			PreserveCase:   true,
+		}),
+	}
+}
+
+func (m HostModel) Init() tea.Cmd {
+	return m.input.Init()
+}
+
+func (m HostModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case input.SubmitMsg:
+		return m, func() tea.Msg { return types.HostRoomMsg{Password: msg.Value} }
+	case input.HideMsg:
+		return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} }
+	default:
+		updatedInput, cmd := m.input.Update(msg)
+		m.input = updatedInput.(input.Model)
+		return m, cmd
+	}
+}
+
+func (m HostModel) View() string {
+	return m.input.View()
+}
@@ -5,52 +5,167 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/givensuman/teletyperacer/client/internal/tui/components/input"
 	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
+	zone "github.com/lrstanley/bubblezone"
 )
 
+// roomListRows is how many public rooms the browser shows at once
+const roomListRows = 6
+
 type JoinModel struct {
-	input input.Model
+	input    input.Model
+	spectate bool                // watch the room instead of racing in it
+	rooms    []types.RoomSummary // public rooms, as last listed by the server
+	selected int                 // index into rooms, or -1 while typing a code
+	loaded   bool                // the server has listed the rooms at least once
+
+	code         string // room last asked for
+	withPassword bool   // the last attempt gave a password
+	prompting    bool   // the input asks for code's password
 }
 
 func NewJoin() JoinModel {
-	config := input.Config{
+	return JoinModel{
+		input:    codeInput("Enter Room Code"),
+		selected: -1,
+	}
+}
+
+func codeInput(label string) input.Model {
+	return input.NewInput(input.Config{
 		Placeholder:    "Enter room code",
-		Label:          "Enter Room Code",
+		Label:          label,
 		SubmittedLabel: "Joining Room...",
 		SubmittedText:  "Attempting to join room",
 		CharLimit:      6,
-	}
-	return JoinModel{
-		input: input.NewInput(config),
-	}
+	})
+}
+
+func passwordInput(label string) input.Model {
+	return input.NewInput(input.Config{
+		Placeholder:    "Room password",
+		Label:          label,
+		SubmittedLabel: "Joining Room...",
+		SubmittedText:  "Checking the password",
+		CharLimit:      protocol.MaxPasswordLength,
+		PreserveCase:   true,
+		Secret:         true,
+	})
 }
 
 func (m JoinModel) Init() tea.Cmd {
-	return m.input.Init()
+	return tea.Batch(m.input.Init(), listRooms)
+}
+
+func listRooms() tea.Msg {
+	return types.ListRoomsMsg{}
+}
+
+// join enters the room with the given code, or watches it
+func (m JoinModel) join(code, password string) (JoinModel, tea.Cmd) {
+	m.code = code
+	m.withPassword = password != ""
+	if m.spectate {
+		return m, func() tea.Msg { return types.SpectateRoomMsg{Code: code, Password: password} }
+	}
+	return m, func() tea.Msg { return types.JoinRoomMsg{Code: code, Password: password} }
 }
 
 func (m JoinModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case input.SubmitMsg:
-		return m, func() tea.Msg {
-			return types.JoinRoomMsg{Code: strings.ToUpper(msg.Value)}
+		if m.prompting {
+			return m.join(m.code, msg.Value)
+		}
+		return m.join(strings.ToUpper(msg.Value), "")
+	case types.RoomListMsg:
+		// Keep the same room selected as the list reorders
+		selectedCode := ""
+		if m.selected >= 0 {
+			selectedCode = m.rooms[m.selected].Code
 		}
+		m.rooms = msg.Rooms
+		m.loaded = true
+		m.selected = -1
+		for i, r := range m.rooms {
+			if r.Code == selectedCode {
+				m.selected = i
+			}
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab":
+			m.spectate = !m.spectate
+			return m, nil
+		case "up":
+			if !m.prompting {
+				m.selected = max(m.selected-1, -1)
+			}
+			return m, nil
+		case "down":
+			if !m.prompting {
+				m.selected = min(m.selected+1, len(m.rooms)-1)
+			}
+			return m, nil
+		case "ctrl+r":
+			return m, listRooms
+		case "enter":
+			if m.selected >= 0 && !m.prompting {
+				return m.join(m.rooms[m.selected].Code, "")
+			}
+		}
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeyBackspace {
+			// Typing goes back to the code entry
+			m.selected = -1
+		}
+		updatedInput, cmd := m.input.Update(msg)
+		m.input = updatedInput.(input.Model)
+		return m, cmd
+	case tea.MouseMsg:
+		switch {
+		case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+			for i := range m.rooms {
+				if zone.Get(fmt.Sprintf("room-%d", i)).InBounds(msg) {
+					m.selected = i
+					return m.join(m.rooms[i].Code, "")
+				}
+			}
+		case msg.Button == tea.MouseButtonWheelUp:
+			m.selected = max(m.selected-1, -1)
+		case msg.Button == tea.MouseButtonWheelDown:
+			m.selected = min(m.selected+1, len(m.rooms)-1)
+		}
+		return m, nil
 	case input.HideMsg:
+		if m.prompting {
+			// Back out of the password to pick another room
+			m.prompting = false
+			m.input = codeInput("Enter Room Code")
+			return m, m.input.Init()
+		}
 		return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} }
 	case types.RoomJoinedMsg:
 		// Successfully joined room, go to lobby as player
 		return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.LobbyScreen} }
 	case types.RoomJoinFailedMsg:
-		// Join failed, reset input and show error
-		m.input = input.NewInput(input.Config{
-			Placeholder:    "Enter room code",
-			Label:          fmt.Sprintf("Join Failed: %s", msg.Reason),
-			SubmittedLabel: "Joining Room...",
-			SubmittedText:  "Attempting to join room",
-			CharLimit:      6,
-		})
-		return m, m.input.Init()
+		if msg.Reason == protocol.ReasonWrongPassword {
+			m.prompting = true
+			label := fmt.Sprintf("Password for Room %s", m.code)
+			if m.withPassword {
+				label = fmt.Sprintf("Wrong Password for Room %s", m.code)
+			}
+			m.input = passwordInput(label)
+			return m, m.input.Init()
+		}
+		// Join failed, reset input and show error. The room may
+		// have filled or closed, so the list is refreshed too.
+		m.prompting = false
+		m.input = codeInput(fmt.Sprintf("Join Failed: %s", joinFailureText(msg)))
+		return m, tea.Batch(m.input.Init(), listRooms)
 	default:
 		var cmd tea.Cmd
 		updatedInput, cmd := m.input.Update(msg)
@@ -59,6 +174,91 @@ func (m JoinModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// joinFailureText explains a refused join to the player
+func joinFailureText(msg types.RoomJoinFailedMsg) string {
+	switch msg.Reason {
+	case protocol.ReasonRoomNotFound:
+		return "no room with that code"
+	case protocol.ReasonRoomFull:
+		return "room is full"
+	case protocol.ReasonRoomLocked:
+		return "room is locked"
+	case protocol.ReasonRaceInProgress:
+		return "a race is in progress"
+	}
+	if msg.Message != "" {
+		return msg.Message
+	}
+	return msg.Reason
+}
+
 func (m JoinModel) View() string {
-	return m.input.View()
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	mode := "[ ] watch as a spectator (tab)"
+	if m.spectate {
+		mode = "[x] watch as a spectator (tab)"
+	}
+	return lipgloss.JoinVertical(lipgloss.Center,
+		m.input.View(),
+		m.browserView(),
+		helpStyle.Render(mode),
+	)
+}
+
+// browserView lists the public rooms around the selected one
+func (m JoinModel) browserView() string {
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.ANSIColor(4)).
+		Padding(0, 1).
+		Width(50)
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.ANSIColor(4))
+
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Public Rooms")}
+	switch {
+	case !m.loaded:
+		lines = append(lines, helpStyle.Render("Loading rooms..."))
+	case len(m.rooms) == 0:
+		lines = append(lines, helpStyle.Render("No public rooms right now"))
+	default:
+		start := max(0, m.selected-roomListRows+1)
+		end := min(len(m.rooms), start+roomListRows)
+		for i := start; i < end; i++ {
+			row := "  " + roomRow(m.rooms[i])
+			if i == m.selected {
+				row = selectedStyle.Render("> " + roomRow(m.rooms[i]))
+			}
+			lines = append(lines, zone.Mark(fmt.Sprintf("room-%d", i), row))
+		}
+		if len(m.rooms) > roomListRows {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("%d-%d of %d", start+1, end, len(m.rooms))))
+		}
+	}
+	lines = append(lines, "", helpStyle.Render("↑/↓ select • enter join • ctrl+r refresh"))
+	return boxStyle.Render(strings.Join(lines, "\n"))
+}
+
+// roomRow describes a public room in a single line
+func roomRow(r types.RoomSummary) string {
+	status := "open"
+	switch r.Phase {
+	case types.PhaseCountdown, types.PhaseRacing:
+		status = "racing"
+	case types.PhaseFinished:
+		status = "results"
+	}
+	host := []rune(r.Host)
+	if len(host) > 12 {
+		host = append(host[:11], '…')
+	}
+	length := r.Settings.Length
+	if length == "" {
+		length = "any"
+	}
+	row := fmt.Sprintf("%-6s  %-12s  %2d/%-2d  %-7s  %s", r.Code, string(host), r.PlayerCount, r.Settings.MaxPlayers, status, length)
+	if r.Password {
+		row += " 🔒"
+	}
+	return row
 }
@@ -2,16 +2,19 @@ package screens
 
 import (
 	"fmt"
-	"math/rand"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/givensuman/teletyperacer/client/internal/tui/components/chat"
 	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
 )
 
-const MaxPlayers = 10
+// slotWidth fits the longest nickname plus its "(host)" label,
+// ready mark, points and rating
+const slotWidth = 40
 
 type LobbyMode int
 
@@ -23,44 +26,100 @@ const (
 type LobbyModel struct {
 	mode        LobbyMode
 	joinCode    string
-	playerCount int
-	playerIndex int // 0-based index of current player
-	lastVersion int // last received state version
+	password    string // chosen by the host when creating the room
+	roster      []types.Player
+	playerIndex int // index of the current player in the roster
+	selected    int // roster position the host's controls act on
+	locked      bool
+	rounds      int  // races completed in the room
+	spectating  bool // watching without a slot
+	spectators  int  // clients watching the room
+	lastVersion int  // last received state version
+	phase       types.RacePhase
+	countdown   int    // seconds left before the race starts
+	notice      string // last error reported by the server
+	autoStart   types.AutoStart
+	autoStartAt time.Time // when the server will start the race on its own, zero if not pending
+	chat        chat.Model
+
+	settings       types.RoomSettings
+	languages      []string // passage languages the server offers
+	settingsOpen   bool
+	settingsCursor int
 }
 
-func generateJoinCode() string {
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	rand.Seed(time.Now().UnixNano())
-	code := make([]byte, 6)
-	for i := range code {
-		code[i] = charset[rand.Intn(len(charset))]
-	}
-	return string(code)
+// countdownTickMsg decrements the lobby's race countdown
+type countdownTickMsg struct{}
+
+func countdownTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return countdownTickMsg{} })
+}
+
+// autoStartTickMsg redraws the lobby while an automatic start is pending
+type autoStartTickMsg struct{}
+
+func autoStartTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return autoStartTickMsg{} })
 }
 
-func NewHostLobby() LobbyModel {
+func NewHostLobby(languages []string, password string) LobbyModel {
 	return LobbyModel{
 		mode:        HostMode,
-		joinCode:    generateJoinCode(),
-		playerCount: 1, // Host is automatically a player
-		playerIndex: 0, // Host is always P1
+		joinCode:    "", // Assigned by the server
+		password:    password,
+		playerIndex: 0, // Host is always the first player
 		lastVersion: -1,
+		chat:        newLobbyChat("press T to chat • pgup/pgdn scroll"),
+		settings:    types.RoomSettings{MaxPlayers: protocol.MaxPlayers},
+		languages:   languages,
 	}
 }
 
-func NewPlayerLobby(code string) LobbyModel {
+func NewPlayerLobby(code string, languages []string) LobbyModel {
 	return LobbyModel{
 		mode:        PlayerMode,
 		joinCode:    code,
-		playerCount: 0,  // Will be updated by server
 		playerIndex: -1, // Will be updated by server
 		lastVersion: -1,
+		chat:        newLobbyChat("press T to chat • pgup/pgdn scroll"),
+		settings:    types.RoomSettings{MaxPlayers: protocol.MaxPlayers},
+		languages:   languages,
 	}
 }
 
+// NewSpectatorLobby watches the room with the given code
+func NewSpectatorLobby(code string, languages []string) LobbyModel {
+	m := NewPlayerLobby(code, languages)
+	m.spectating = true
+	m.chat = newLobbyChat("only players can chat • pgup/pgdn scroll")
+	return m
+}
+
+// IsSpectating reports whether the player is watching the room
+// rather than racing in it
+func (m LobbyModel) IsSpectating() bool {
+	return m.spectating
+}
+
+func newLobbyChat(hint string) chat.Model {
+	return chat.NewChat(chat.Config{
+		Placeholder: "Say something...",
+		CharLimit:   protocol.MaxChatLength,
+		Height:      6,
+		Width:       2*slotWidth + 4,
+		Hint:        hint,
+	})
+}
+
+// chatLine formats a chat message for the chat panel
+func chatLine(c types.ChatMessage) chat.Line {
+	return chat.Line{Name: c.Name, Color: playerColor(c.Color), Text: c.Text, At: c.SentAt}
+}
+
 func (m LobbyModel) Init() tea.Cmd {
 	if m.mode == HostMode {
-		return func() tea.Msg { return types.CreateRoomMsg{} }
+		password := m.password
+		return func() tea.Msg { return types.CreateRoomMsg{Password: password} }
 	} else if m.mode == PlayerMode {
 		// Request current room state when joining as player
 		return func() tea.Msg { return types.GetRoomStateMsg{Code: m.joinCode} }
@@ -71,11 +130,71 @@ func (m LobbyModel) Init() tea.Cmd {
 func (m LobbyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.chat.Focused() || msg.Type == tea.KeyPgUp || msg.Type == tea.KeyPgDown {
+			// Typing in the chat shouldn't trigger lobby shortcuts
+			updated, cmd := m.chat.Update(msg)
+			m.chat = updated.(chat.Model)
+			return m, cmd
+		}
+		if m.settingsOpen {
+			return m.updateSettings(msg)
+		}
 		switch msg.String() {
+		case "t", "enter":
+			if m.spectating {
+				// Only players can chat
+				break
+			}
+			var cmd tea.Cmd
+			m.chat, cmd = m.chat.Focus()
+			return m, cmd
 		case "q", "ctrl+c":
 			return m, tea.Quit
 		case "esc":
-			return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} }
+			return m, tea.Sequence(
+				func() tea.Msg { return types.LeaveRoomMsg{} },
+				func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} },
+			)
+		case "s":
+			if m.IsHost() && (m.phase == types.PhaseWaiting || m.phase == types.PhaseFinished) {
+				m.notice = ""
+				return m, func() tea.Msg { return types.StartRaceMsg{} }
+			}
+		case "up", "k":
+			if m.IsHost() && m.selected > 0 {
+				m.selected--
+			}
+		case "down", "j":
+			if m.IsHost() && m.selected < len(m.roster)-1 {
+				m.selected++
+			}
+		case "r":
+			if me, ok := m.me(); ok && (m.phase == types.PhaseWaiting || m.phase == types.PhaseFinished) {
+				ready := !me.Ready
+				return m, func() tea.Msg { return types.SetReadyMsg{Ready: ready} }
+			}
+		case "o":
+			if m.IsHost() {
+				m.settingsOpen = true
+			}
+		case "l":
+			if m.IsHost() {
+				locked := !m.locked
+				return m, func() tea.Msg { return types.LockRoomMsg{Locked: locked} }
+			}
+		case "x":
+			if target, ok := m.selectedOther(); ok {
+				return m, func() tea.Msg { return types.KickPlayerMsg{PlayerIndex: target.Index} }
+			}
+		case "h":
+			if target, ok := m.selectedOther(); ok {
+				return m, func() tea.Msg { return types.TransferHostMsg{PlayerIndex: target.Index} }
+			}
+		case "p":
+			if m.spectating && m.phase != types.PhaseCountdown && m.phase != types.PhaseRacing {
+				code := m.joinCode
+				return m, func() tea.Msg { return types.JoinRoomMsg{Code: code} }
+			}
 		case "c":
 			if m.joinCode != "" {
 				// Try to copy to clipboard
@@ -87,16 +206,12 @@ func (m LobbyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case types.RoomCreatedMsg:
 		// Server confirmed room creation
 		if m.mode == HostMode {
-			m.playerCount = 1 // Host is the first player
-			m.playerIndex = 0 // Host is always at index 0
+			m.joinCode = msg.Code
 		}
 	case types.RoomJoinedMsg:
 		// Successfully joined room as player
 		if m.mode == PlayerMode {
 			m.joinCode = msg.Code
-			// Temporarily assume we're the only player until room state arrives
-			m.playerCount = 1
-			m.playerIndex = 0
 		}
 
 	case types.RoomStateMsg:
@@ -104,14 +219,69 @@ func (m LobbyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Version > m.lastVersion {
 			m.lastVersion = msg.Version
 			m.joinCode = msg.Code
-			m.playerCount = msg.PlayerCount
+			m.roster = msg.Players
 			m.playerIndex = msg.YourIndex
-			// Validate yourIndex
-			if m.playerIndex < 0 || m.playerIndex >= m.playerCount {
-				// Invalid, but for now, set to 0 or something
-				m.playerIndex = 0
+			m.phase = msg.Phase
+			m.locked = msg.Locked
+			m.rounds = msg.Rounds
+			m.spectating = msg.Spectating
+			m.spectators = msg.Spectators
+			if m.selected >= len(m.roster) {
+				m.selected = max(len(m.roster)-1, 0)
+			}
+			m.autoStart = msg.AutoStart
+			m.settings = msg.Settings
+			m.settingsOpen = m.settingsOpen && m.IsHost()
+			wasPending := !m.autoStartAt.IsZero()
+			m.autoStartAt = time.Time{}
+			if msg.StartingIn > 0 {
+				m.autoStartAt = time.Now().Add(time.Duration(msg.StartingIn) * time.Second)
+				if !wasPending {
+					return m, autoStartTick()
+				}
 			}
 		}
+
+	case types.RacePhaseMsg:
+		m.phase = msg.Phase
+		m.autoStartAt = time.Time{}
+		if msg.Phase == types.PhaseCountdown {
+			m.countdown = msg.Countdown
+			return m, countdownTick()
+		}
+
+	case countdownTickMsg:
+		if m.phase == types.PhaseCountdown && m.countdown > 1 {
+			m.countdown--
+			return m, countdownTick()
+		}
+
+	case chat.SendMsg:
+		return m, func() tea.Msg { return types.SendChatMsg{Text: msg.Text} }
+
+	case types.ChatReceivedMsg:
+		m.chat = m.chat.Append(chatLine(msg.Message))
+
+	case types.ChatHistoryMsg:
+		lines := make([]chat.Line, 0, len(msg.Messages))
+		for _, message := range msg.Messages {
+			lines = append(lines, chatLine(message))
+		}
+		m.chat = m.chat.Append(lines...)
+
+	case autoStartTickMsg:
+		if !m.autoStartAt.IsZero() {
+			return m, autoStartTick()
+		}
+
+	case types.ServerErrorMsg:
+		m.notice = msg.Message
+
+	default:
+		// Keep the chat cursor blinking
+		updated, cmd := m.chat.Update(msg)
+		m.chat = updated.(chat.Model)
+		return m, cmd
 	}
 
 	return m, nil
@@ -121,6 +291,67 @@ func (m LobbyModel) GetJoinCode() string {
 	return m.joinCode
 }
 
+func (m LobbyModel) GetRoster() []types.Player {
+	return m.roster
+}
+
+func (m LobbyModel) GetPlayerIndex() int {
+	return m.playerIndex
+}
+
+// IsHost reports whether the current player hosts the room
+func (m LobbyModel) IsHost() bool {
+	for _, p := range m.roster {
+		if p.Index == m.playerIndex {
+			return p.Host
+		}
+	}
+	return m.mode == HostMode
+}
+
+// me returns the current player's roster entry
+func (m LobbyModel) me() (types.Player, bool) {
+	for _, p := range m.roster {
+		if p.Index == m.playerIndex {
+			return p, true
+		}
+	}
+	return types.Player{}, false
+}
+
+// setAutoStart asks the server for new auto-start options,
+// keeping the delay within the bounds it accepts
+func (m LobbyModel) setAutoStart(autoStart types.AutoStart) tea.Cmd {
+	autoStart.Delay = min(max(autoStart.Delay, protocol.MinAutoStartDelay), protocol.MaxAutoStartDelay)
+	return func() tea.Msg { return types.SetAutoStartMsg{AutoStart: autoStart} }
+}
+
+// selectedOther returns the player the host has selected, provided
+// the current player is the host and has selected someone else
+func (m LobbyModel) selectedOther() (types.Player, bool) {
+	if !m.IsHost() || m.selected >= len(m.roster) {
+		return types.Player{}, false
+	}
+	target := m.roster[m.selected]
+	return target, target.Index != m.playerIndex
+}
+
+// autoStartSummary describes the room's auto-start options,
+// or returns "" when the room only starts on the host's word
+func (m LobbyModel) autoStartSummary() string {
+	var triggers []string
+	if m.autoStart.WhenAllReady {
+		triggers = append(triggers, "when everyone is ready")
+	}
+	if m.autoStart.MinPlayers > 0 {
+		triggers = append(triggers, fmt.Sprintf("at %d players", m.autoStart.MinPlayers))
+	}
+	if len(triggers) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Auto-start %s, after %ds", strings.Join(triggers, " or "), m.autoStart.Delay)
+}
+
 // ANSI colors for players
 var playerColors = []lipgloss.Color{
 	lipgloss.Color("1"),  // Red
@@ -135,10 +366,17 @@ var playerColors = []lipgloss.Color{
 	lipgloss.Color("11"), // Bright Yellow
 }
 
+// playerColor returns the color for a server-assigned palette slot
+func playerColor(slot int) lipgloss.Color {
+	return playerColors[slot%len(playerColors)]
+}
+
 func (m LobbyModel) View() string {
 	var content strings.Builder
 
-	if m.mode == HostMode {
+	if m.spectating {
+		content.WriteString("👀 Spectating\n\n")
+	} else if m.IsHost() {
 		content.WriteString("🎯 Host Lobby\n\n")
 	} else {
 		content.WriteString("🎯 Player Lobby\n\n")
@@ -147,7 +385,11 @@ func (m LobbyModel) View() string {
 	if m.joinCode != "" {
 		content.WriteString(fmt.Sprintf("Join Code: %s\n", m.joinCode))
 		content.WriteString("(press 'c' to copy to clipboard)\n\n")
-		if m.mode == HostMode {
+		if m.locked {
+			content.WriteString("🔒 Room is locked to new players\n\n")
+		} else if m.IsHost() && m.password != "" {
+			content.WriteString("🔑 Share this code and the password with friends to join!\n\n")
+		} else if m.IsHost() {
 			content.WriteString("Share this code with friends to join!\n\n")
 		}
 	} else {
@@ -158,26 +400,40 @@ func (m LobbyModel) View() string {
 		}
 	}
 
-	// Create player grid (2 columns x 5 rows)
-	playerSlots := make([]string, MaxPlayers)
-	for i := 0; i < MaxPlayers; i++ {
-		if i < m.playerCount {
-			displayName := fmt.Sprintf("P%d", i+1)
+	// Create player grid (2 columns, one slot per place in the room)
+	slots := max(m.settings.MaxPlayers, len(m.roster))
+	playerSlots := make([]string, slots)
+	for i := 0; i < slots; i++ {
+		if i < len(m.roster) {
+			p := m.roster[i]
+			displayName := p.Name
 
 			// Add special labels for current player and host
-			if i == m.playerIndex {
+			if p.Index == m.playerIndex {
 				displayName += " (you)"
-			} else if m.mode == HostMode && i == 0 {
+			} else if p.Host {
 				displayName += " (host)"
 			}
+			if p.Ready {
+				displayName += " ✓"
+			}
+			if m.rounds > 0 {
+				displayName += fmt.Sprintf(" %dpt", p.Points)
+			}
+			if p.Rating > 0 {
+				displayName += " · " + ratingText(p.Rating, p.RatingChange)
+			}
 
 			// Style the player slot
 			playerStyle := lipgloss.NewStyle().
-				Foreground(playerColors[i%len(playerColors)]).
+				Foreground(playerColor(p.Color)).
 				Background(lipgloss.Color("236")). // Dark gray background
 				Padding(0, 1).
 				Align(lipgloss.Center).
-				Width(15)
+				Width(slotWidth)
+			if m.IsHost() && i == m.selected {
+				playerStyle = playerStyle.Reverse(true)
+			}
 
 			playerSlots[i] = playerStyle.Render(displayName)
 		} else {
@@ -187,7 +443,7 @@ func (m LobbyModel) View() string {
 				Background(lipgloss.Color("235")).
 				Padding(0, 1).
 				Align(lipgloss.Center).
-				Width(15)
+				Width(slotWidth)
 
 			playerSlots[i] = emptyStyle.Render("Empty")
 		}
@@ -195,8 +451,8 @@ func (m LobbyModel) View() string {
 
 	// Arrange in 2 columns
 	var leftColumn, rightColumn []string
-	for i := 0; i < MaxPlayers; i++ {
-		if i < 5 {
+	for i := 0; i < slots; i++ {
+		if i < (slots+1)/2 {
 			leftColumn = append(leftColumn, playerSlots[i])
 		} else {
 			rightColumn = append(rightColumn, playerSlots[i])
@@ -211,18 +467,76 @@ func (m LobbyModel) View() string {
 
 	playerGrid := lipgloss.JoinHorizontal(lipgloss.Top, leftCol, rightCol)
 
-	content.WriteString("Players:\n\n")
+	if m.rounds > 0 {
+		races := "races"
+		if m.rounds == 1 {
+			races = "race"
+		}
+		content.WriteString(fmt.Sprintf("Players (points after %d %s):\n\n", m.rounds, races))
+	} else {
+		content.WriteString("Players:\n\n")
+	}
 	content.WriteString(playerGrid)
 	content.WriteString("\n\n")
+	if m.spectators > 0 {
+		content.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(fmt.Sprintf("👀 %d watching", m.spectators)) + "\n\n")
+	}
+	if m.settingsOpen {
+		content.WriteString(m.settingsView())
+	} else {
+		content.WriteString(m.chat.View())
+	}
+	content.WriteString("\n\n")
+	content.WriteString(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render(m.settingsSummary()) + "\n\n")
 
-	if m.mode == HostMode {
-		if m.playerCount >= MaxPlayers {
-			content.WriteString("Room is full!\n\n")
+	if autoStart := m.autoStartSummary(); autoStart != "" {
+		content.WriteString(autoStart + "\n\n")
+	}
+	if !m.autoStartAt.IsZero() {
+		left := max(int((time.Until(m.autoStartAt)+time.Second-1)/time.Second), 1)
+		content.WriteString(fmt.Sprintf("Starting automatically in %d...\n\n", left))
+	}
+
+	switch m.phase {
+	case types.PhaseCountdown:
+		content.WriteString(fmt.Sprintf("Race starting in %d...\n\n", m.countdown))
+	case types.PhaseRacing:
+		content.WriteString("Race in progress!\n\n")
+	case types.PhaseFinished:
+		if m.IsHost() {
+			content.WriteString("Race finished! Press S to race again.\n\n")
 		} else {
-			content.WriteString("Waiting for players to join...\n\n")
+			content.WriteString("Race finished! Waiting for host to start another...\n\n")
 		}
+	default:
+		if m.IsHost() {
+			if len(m.roster) >= m.settings.MaxPlayers {
+				content.WriteString("Room is full! Press S to start the race.\n\n")
+			} else {
+				content.WriteString("Waiting for players to join... Press S to start the race.\n\n")
+			}
+		} else {
+			content.WriteString("Waiting for host to start...\n\n")
+		}
+	}
+
+	if m.notice != "" {
+		content.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("1")).
+			Render(m.notice) + "\n\n")
+	}
+	if m.spectating {
+		content.WriteString("P join the next race\n")
 	} else {
-		content.WriteString("Waiting for host to start...\n\n")
+		content.WriteString("R ready/unready\n")
+	}
+	if m.IsHost() {
+		content.WriteString("↑/↓ select player • X kick • H make host • L lock/unlock\n")
+		content.WriteString("O room settings and auto-start\n")
 	}
 	content.WriteString("Press ESC to go back to Home • Press Q to quit")
 
@@ -0,0 +1,225 @@
+package screens
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// passageLengths are the length settings a room can pick from,
+// with "" meaning any length
+var passageLengths = []string{"", "short", "medium", "long"}
+
+// settingsRow is one line of the host's settings panel. Adjust
+// returns the command that asks the server for the changed value.
+type settingsRow struct {
+	label  string
+	value  func(m LobbyModel) string
+	adjust func(m LobbyModel, step int) tea.Cmd
+}
+
+var settingsRows = []settingsRow{
+	{
+		label: "Max players",
+		value: func(m LobbyModel) string { return fmt.Sprint(m.settings.MaxPlayers) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			lowest := max(protocol.MinPlayers, len(m.roster))
+			settings.MaxPlayers = min(max(settings.MaxPlayers+step, lowest), protocol.MaxPlayers)
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Passage length",
+		value: func(m LobbyModel) string { return orAny(m.settings.Length) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			settings.Length = cycle(passageLengths, settings.Length, step)
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Language",
+		value: func(m LobbyModel) string { return orAny(m.settings.Language) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			settings.Language = cycle(append([]string{""}, m.languages...), settings.Language, step)
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Punctuation",
+		value: func(m LobbyModel) string { return onOff(m.settings.Punctuation) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			settings.Punctuation = !settings.Punctuation
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Numbers",
+		value: func(m LobbyModel) string { return onOff(m.settings.Numbers) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			settings.Numbers = !settings.Numbers
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Countdown",
+		value: func(m LobbyModel) string {
+			if m.settings.Countdown == 0 {
+				return "default"
+			}
+			return fmt.Sprintf("%ds", m.settings.Countdown)
+		},
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			switch countdown := settings.Countdown + step; {
+			case settings.Countdown == 0 && step > 0:
+				settings.Countdown = protocol.MinCountdown
+			case countdown < protocol.MinCountdown:
+				settings.Countdown = 0
+			default:
+				settings.Countdown = min(countdown, protocol.MaxCountdown)
+			}
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Visibility",
+		value: func(m LobbyModel) string {
+			if m.settings.Public {
+				return "public"
+			}
+			return "private"
+		},
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			settings := m.settings
+			settings.Public = !settings.Public
+			return m.setSettings(settings)
+		},
+	},
+	{
+		label: "Auto-start when all ready",
+		value: func(m LobbyModel) string { return onOff(m.autoStart.WhenAllReady) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			autoStart := m.autoStart
+			autoStart.WhenAllReady = !autoStart.WhenAllReady
+			return m.setAutoStart(autoStart)
+		},
+	},
+	{
+		label: "Auto-start at players",
+		value: func(m LobbyModel) string {
+			if m.autoStart.MinPlayers == 0 {
+				return "off"
+			}
+			return fmt.Sprint(m.autoStart.MinPlayers)
+		},
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			autoStart := m.autoStart
+			autoStart.MinPlayers = min(max(autoStart.MinPlayers+step, 0), m.settings.MaxPlayers)
+			return m.setAutoStart(autoStart)
+		},
+	},
+	{
+		label: "Auto-start delay",
+		value: func(m LobbyModel) string { return fmt.Sprintf("%ds", m.autoStart.Delay) },
+		adjust: func(m LobbyModel, step int) tea.Cmd {
+			autoStart := m.autoStart
+			autoStart.Delay += step
+			return m.setAutoStart(autoStart)
+		},
+	},
+}
+
+// updateSettings handles keys while the settings panel is open
+func (m LobbyModel) updateSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.settingsCursor = max(m.settingsCursor-1, 0)
+	case "down", "j":
+		m.settingsCursor = min(m.settingsCursor+1, len(settingsRows)-1)
+	case "left", "h":
+		return m, settingsRows[m.settingsCursor].adjust(m, -1)
+	case "right", "l", " ":
+		return m, settingsRows[m.settingsCursor].adjust(m, 1)
+	case "o", "esc", "enter":
+		m.settingsOpen = false
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m LobbyModel) setSettings(settings types.RoomSettings) tea.Cmd {
+	return func() tea.Msg { return types.SetSettingsMsg{Settings: settings} }
+}
+
+// settingsView renders the host's settings panel
+func (m LobbyModel) settingsView() string {
+	var rows []string
+	for i, row := range settingsRows {
+		line := fmt.Sprintf("%-26s ◂ %s ▸", row.label, row.value(m))
+		if i == m.settingsCursor {
+			line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.ANSIColor(4)).Render("▶ " + line)
+		} else {
+			line = "  " + line
+		}
+		rows = append(rows, line)
+	}
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render("↑/↓ choose • ←/→ change • esc done")
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.ANSIColor(4)).
+		Padding(0, 1).
+		Align(lipgloss.Left).
+		Render("⚙️ Room Settings\n\n" + strings.Join(rows, "\n") + "\n\n" + help)
+}
+
+// settingsSummary describes the room's settings in one line
+func (m LobbyModel) settingsSummary() string {
+	s := m.settings
+	visibility := "private"
+	if s.Public {
+		visibility = "public"
+	}
+	countdown := "default countdown"
+	if s.Countdown > 0 {
+		countdown = fmt.Sprintf("%ds countdown", s.Countdown)
+	}
+	return fmt.Sprintf("%d players • %s length • %s language • punctuation %s • numbers %s • %s • %s",
+		s.MaxPlayers, orAny(s.Length), orAny(s.Language), onOff(s.Punctuation), onOff(s.Numbers), countdown, visibility)
+}
+
+// cycle steps through options from current, wrapping at either end
+func cycle(options []string, current string, step int) string {
+	i := slices.Index(options, current)
+	if i < 0 {
+		return options[0]
+	}
+	return options[((i+step)%len(options)+len(options))%len(options)]
+}
+
+func orAny(value string) string {
+	if value == "" {
+		return "any"
+	}
+	return value
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,54 @@
+package screens
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/givensuman/teletyperacer/client/internal/tui/components/input"
+	"github.com/givensuman/teletyperacer/client/internal/types"
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+type NicknameModel struct {
+	input input.Model
+}
+
+func NewNickname(current string) NicknameModel {
+	label := "Choose a Nickname"
+	if current != "" {
+		label = "Nickname (currently " + current + ")"
+	}
+	return NicknameModel{
+		input: input.NewInput(input.Config{
+			Placeholder:    "Your nickname",
+			Label:          label,
+			SubmittedLabel: "Saving...",
+			SubmittedText:  "Saving your nickname",
+			CharLimit:      protocol.MaxNameLength,
+			PreserveCase:   true,
+		}),
+	}
+}
+
+func (m NicknameModel) Init() tea.Cmd {
+	return m.input.Init()
+}
+
+func (m NicknameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case input.SubmitMsg:
+		name := strings.TrimSpace(msg.Value)
+		return m, func() tea.Msg { return types.NicknameChangedMsg{Name: name} }
+	case input.HideMsg:
+		return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} }
+	default:
+		updatedInput, cmd := m.input.Update(msg)
+		m.input = updatedInput.(input.Model)
+		return m, cmd
+	}
+}
+
+func (m NicknameModel) View() string {
+	return m.input.View()
+}
@@ -12,11 +12,12 @@ type PracticeModel struct {
 	typing typing.Model
 }
 
+// SampleText is typed when no other passage is available
+const SampleText = "The quick brown fox jumps over the lazy dog. This is a sample text for typing practice. Try to type as accurately and quickly as possible."
+
 func NewPractice() PracticeModel {
-	// Sample text for practice
-	sampleText := "The quick brown fox jumps over the lazy dog. This is a sample text for typing practice. Try to type as accurately and quickly as possible."
 	return PracticeModel{
-		typing: typing.NewTyping(sampleText),
+		typing: typing.NewTyping(SampleText),
 	}
 }
 
@@ -0,0 +1,266 @@
+package screens
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/givensuman/teletyperacer/client/internal/tui/components/typing"
+	"github.com/givensuman/teletyperacer/client/internal/types"
+)
+
+// trackWidth is the number of cells in a player's progress track
+const trackWidth = 40
+
+// labelWidth fits the longest nickname plus its "(you)" label
+const labelWidth = 23
+
+// track is the live state of one player's race
+type track struct {
+	position int
+	wpm      float64
+	place    int // finishing position, 0 while still racing
+}
+
+type RaceModel struct {
+	passage     types.Passage
+	textLen     int
+	typing      typing.Model
+	roster      []types.Player
+	playerIndex int           // index of the current player in the roster
+	tracks      map[int]track // playerIndex -> track
+	phase       types.RacePhase
+	countdown   int  // seconds left before the race starts
+	spectating  bool // watching without typing
+	width       int
+	height      int
+}
+
+func NewRace(passage types.Passage, roster []types.Player, playerIndex int) RaceModel {
+	return RaceModel{
+		passage:     passage,
+		textLen:     len([]rune(passage.Text)),
+		typing:      typing.NewTyping(passage.Text),
+		roster:      roster,
+		playerIndex: playerIndex,
+		tracks:      make(map[int]track),
+		phase:       types.PhaseWaiting,
+	}
+}
+
+// NewSpectatorRace shows a race without taking part in it
+func NewSpectatorRace(passage types.Passage, roster []types.Player, phase types.RacePhase) RaceModel {
+	m := NewRace(passage, roster, -1)
+	m.spectating = true
+	m.phase = phase
+	return m
+}
+
+// Resume puts the race back where it was when the connection dropped
+func (m RaceModel) Resume(phase types.RacePhase, position int) RaceModel {
+	m.phase = phase
+	m.typing = typing.NewTypingAt(m.passage.Text, position)
+	t := m.tracks[m.playerIndex]
+	t.position = position
+	m.tracks[m.playerIndex] = t
+	return m
+}
+
+func (m RaceModel) Init() tea.Cmd {
+	return m.typing.Init()
+}
+
+func (m RaceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		updatedTyping, cmd := m.typing.Update(msg)
+		m.typing = updatedTyping.(typing.Model)
+		return m, cmd
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.LobbyScreen} }
+		}
+		// Only accept typing once the race is on
+		if m.spectating || m.phase != types.PhaseRacing || m.typing.IsCompleted() {
+			return m, nil
+		}
+		updatedTyping, cmd := m.typing.Update(msg)
+		m.typing = updatedTyping.(typing.Model)
+		return m, cmd
+
+	case types.RacePhaseMsg:
+		m.phase = msg.Phase
+		switch msg.Phase {
+		case types.PhaseCountdown:
+			m.countdown = msg.Countdown
+			return m, countdownTick()
+		case types.PhaseRacing:
+			// Restart the typing model so WPM is measured from the start signal
+			width, height := m.width, m.height
+			m.typing = typing.NewTyping(m.passage.Text)
+			if width > 0 {
+				updatedTyping, _ := m.typing.Update(tea.WindowSizeMsg{Width: width, Height: height})
+				m.typing = updatedTyping.(typing.Model)
+			}
+		}
+
+	case countdownTickMsg:
+		if m.phase == types.PhaseCountdown && m.countdown > 1 {
+			m.countdown--
+			return m, countdownTick()
+		}
+
+	case types.RoomStateMsg:
+		m.roster = msg.Players
+		m.playerIndex = msg.YourIndex
+		m.phase = msg.Phase
+
+	case typing.ProgressMsg:
+		t := m.tracks[m.playerIndex]
+		t.position = msg.Position
+		t.wpm = msg.WPM
+		m.tracks[m.playerIndex] = t
+
+	case typing.TypingCompletedMsg:
+		return m, func() tea.Msg { return types.FinishRaceMsg{} }
+
+	case types.PlayerProgressMsg:
+		t := m.tracks[msg.PlayerIndex]
+		t.position = msg.Position
+		t.wpm = msg.WPM
+		m.tracks[msg.PlayerIndex] = t
+
+	case types.PlayerFinishedMsg:
+		t := m.tracks[msg.PlayerIndex]
+		t.place = msg.Place
+		if t.place > 0 {
+			t.position = m.textLen
+		}
+		m.tracks[msg.PlayerIndex] = t
+	}
+
+	return m, nil
+}
+
+// renderTrack draws one player's lane
+func (m RaceModel) renderTrack(p types.Player) string {
+	color := playerColor(p.Color)
+	t := m.tracks[p.Index]
+
+	label := p.Name
+	if p.Index == m.playerIndex {
+		label += " (you)"
+	}
+
+	filled := 0
+	if m.textLen > 0 {
+		filled = t.position * trackWidth / m.textLen
+	}
+	if filled > trackWidth {
+		filled = trackWidth
+	}
+
+	bar := lipgloss.NewStyle().Foreground(color).Render(strings.Repeat("━", filled)) +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(strings.Repeat("─", trackWidth-filled))
+
+	status := fmt.Sprintf("%5.1f WPM", t.wpm)
+	if t.place > 0 {
+		status += " " + ordinal(t.place)
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Center,
+		lipgloss.NewStyle().Foreground(color).Bold(p.Index == m.playerIndex).Width(labelWidth).Render(label),
+		bar,
+		lipgloss.NewStyle().PaddingLeft(1).Width(16).Render(status),
+	)
+}
+
+// attribution credits the passage's author and source
+func (m RaceModel) attribution() string {
+	switch {
+	case m.passage.Author != "" && m.passage.Source != "":
+		return fmt.Sprintf("— %s, %s", m.passage.Author, m.passage.Source)
+	case m.passage.Source != "":
+		return "— " + m.passage.Source
+	}
+	return ""
+}
+
+// ratingText formats a skill rating with the change from the
+// player's last race, if it moved
+func ratingText(rating, change int) string {
+	if change == 0 {
+		return fmt.Sprint(rating)
+	}
+	return fmt.Sprintf("%d (%+d)", rating, change)
+}
+
+// ordinal formats a finishing position as 1st, 2nd, 3rd...
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+func (m RaceModel) View() string {
+	var content strings.Builder
+
+	if m.spectating {
+		content.WriteString("👀 Spectating\n\n")
+	} else {
+		content.WriteString("🏁 Race\n\n")
+	}
+
+	tracks := make([]string, 0, len(m.roster))
+	for _, p := range m.roster {
+		tracks = append(tracks, m.renderTrack(p))
+	}
+	content.WriteString(lipgloss.JoinVertical(lipgloss.Left, tracks...))
+	content.WriteString("\n\n")
+
+	switch m.phase {
+	case types.PhaseCountdown:
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("Get ready... %d", m.countdown)))
+		content.WriteString("\n\n")
+		content.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Width(labelWidth + trackWidth + 16).Render(m.passage.Text))
+	case types.PhaseRacing:
+		if m.spectating {
+			content.WriteString(lipgloss.NewStyle().Width(labelWidth + trackWidth + 16).Render(m.passage.Text))
+			break
+		}
+		if m.typing.IsCompleted() {
+			content.WriteString(m.typing.View())
+			content.WriteString("\n\nWaiting for other racers to finish...")
+		} else {
+			stats := fmt.Sprintf("WPM: %.1f | Accuracy: %.1f%% | Progress: %.1f%%", m.typing.GetWPM(), m.typing.GetAccuracy(), m.typing.GetProgress())
+			content.WriteString(stats + "\n\n" + m.typing.View())
+		}
+	case types.PhaseFinished:
+		content.WriteString("Race finished!")
+	}
+
+	if attribution := m.attribution(); attribution != "" && m.phase != types.PhaseRacing {
+		content.WriteString("\n\n" + lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("240")).Render(attribution))
+	}
+
+	content.WriteString("\n\nPress ESC to go back to the lobby")
+
+	return lipgloss.NewStyle().
+		Padding(1).
+		Render(content.String())
+}
@@ -0,0 +1,290 @@
+package screens
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	zone "github.com/lrstanley/bubblezone"
+
+	"github.com/givensuman/teletyperacer/client/internal/tui/components/button"
+	"github.com/givensuman/teletyperacer/client/internal/types"
+)
+
+type ResultsModel struct {
+	passage     types.Passage
+	results     []types.RaceResult
+	roster      []types.Player
+	playerIndex int // 0-based index of current player
+	isHost      bool
+	cursor      int
+	choices     [2]button.Model
+}
+
+// resultsButtonWidth fits the longest label the rematch button takes
+const resultsButtonWidth = len("Vote Rematch")
+
+func NewResults(msg types.RaceResultsMsg, roster []types.Player, playerIndex int, isHost bool) ResultsModel {
+	m := ResultsModel{
+		passage:     msg.Passage,
+		results:     msg.Results,
+		roster:      roster,
+		playerIndex: playerIndex,
+		isHost:      isHost,
+	}
+	m.choices = [2]button.Model{
+		m.rematchButton(),
+		button.NewButton("Lobby", func() tea.Msg { return types.ScreenChangeMsg{Screen: types.LobbyScreen} }),
+	}
+	if m.choices[0].IsDisabled() {
+		m.choices[1] = m.choices[1].Focus()
+		m.cursor = 1
+	}
+	return m
+}
+
+// rematchButton lets the host take everyone back to the lobby and
+// everyone else vote for that, toggling their current vote.
+// Spectators have no say.
+func (m ResultsModel) rematchButton() button.Model {
+	if m.playerIndex < 0 {
+		disabled, _ := button.NewButton("Rematch", nil).Update(button.Disable)
+		return disabled.(button.Model)
+	}
+	if m.isHost {
+		return button.NewFocusedButton("Rematch", func() tea.Msg { return types.AcceptRematchMsg{} })
+	}
+	if m.voted() {
+		return button.NewFocusedButton("Unvote", func() tea.Msg { return types.VoteRematchMsg{Vote: false} })
+	}
+	return button.NewFocusedButton("Vote Rematch", func() tea.Msg { return types.VoteRematchMsg{Vote: true} })
+}
+
+// voted reports whether the current player has voted for a rematch
+func (m ResultsModel) voted() bool {
+	for _, p := range m.roster {
+		if p.Index == m.playerIndex {
+			return p.Rematch
+		}
+	}
+	return false
+}
+
+// rematchVotes counts the players who want to race again
+func (m ResultsModel) rematchVotes() int {
+	votes := 0
+	for _, p := range m.roster {
+		if p.Rematch {
+			votes++
+		}
+	}
+	return votes
+}
+
+func (m ResultsModel) Init() tea.Cmd {
+	return func() tea.Msg { return button.WidthMsg(resultsButtonWidth) }
+}
+
+// moveCursor focuses the next enabled button in the given direction
+func (m ResultsModel) moveCursor(direction int) ResultsModel {
+	next := (m.cursor + direction + len(m.choices)) % len(m.choices)
+	if m.choices[next].IsDisabled() {
+		return m
+	}
+	m.choices[m.cursor] = m.choices[m.cursor].Unfocus()
+	m.cursor = next
+	m.choices[m.cursor] = m.choices[m.cursor].Focus()
+	return m
+}
+
+func (m ResultsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case button.WidthMsg:
+		for i, btn := range m.choices {
+			updatedBtn, _ := btn.Update(msg)
+			m.choices[i] = updatedBtn.(button.Model)
+		}
+
+	case types.RoomStateMsg:
+		// Votes and the host role can change while results are shown
+		m.roster = msg.Players
+		m.playerIndex = msg.YourIndex
+		for _, p := range msg.Players {
+			if p.Index == msg.YourIndex {
+				m.isHost = p.Host
+			}
+		}
+		btn, _ := m.rematchButton().Update(button.WidthMsg(resultsButtonWidth))
+		m.choices[0] = btn.(button.Model)
+		if m.cursor != 0 {
+			m.choices[0] = m.choices[0].Unfocus()
+		} else if m.choices[0].IsDisabled() {
+			m.choices[0] = m.choices[0].Unfocus()
+			m.choices[1] = m.choices[1].Focus()
+			m.cursor = 1
+		}
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.LobbyScreen} }
+		case "h", "left", "k", "up", "shift+tab":
+			m = m.moveCursor(-1)
+		case "l", "right", "j", "down", "tab":
+			m = m.moveCursor(1)
+		case "enter":
+			if !m.choices[m.cursor].IsDisabled() {
+				return m, m.choices[m.cursor].GetAction()
+			}
+		}
+
+	case tea.MouseMsg:
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+			for i := range m.choices {
+				if zone.Get(fmt.Sprintf("results-button-%d", i)).InBounds(msg) && !m.choices[i].IsDisabled() {
+					return m, m.choices[i].GetAction()
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// resultFor returns the given player's result, if any
+func (m ResultsModel) resultFor(playerIndex int) (types.RaceResult, bool) {
+	for _, r := range m.results {
+		if r.PlayerIndex == playerIndex {
+			return r, true
+		}
+	}
+	return types.RaceResult{}, false
+}
+
+// podiumWidth is the width of each podium step. Long
+// nicknames wrap above their step.
+const podiumWidth = 18
+
+// renderPodium draws the top three finishers, winner in the middle
+func (m ResultsModel) renderPodium() string {
+	heights := map[int]int{1: 3, 2: 2, 3: 1}
+	var columns []string
+	for _, rank := range []int{2, 1, 3} {
+		if rank > len(m.results) {
+			continue
+		}
+		r := m.results[rank-1]
+		color := playerColor(r.Color)
+
+		label := r.Name
+		if r.PlayerIndex == m.playerIndex {
+			label += " (you)"
+		}
+		stats := fmt.Sprintf("%.1f WPM", r.WPM)
+		block := lipgloss.NewStyle().
+			Background(color).
+			Foreground(lipgloss.Color("0")).
+			Bold(true).
+			Width(podiumWidth).
+			Height(heights[rank]).
+			Align(lipgloss.Center).
+			Render(ordinal(rank))
+
+		columns = append(columns, lipgloss.JoinVertical(lipgloss.Center,
+			lipgloss.NewStyle().Foreground(color).Bold(true).Width(podiumWidth).Align(lipgloss.Center).Render(label),
+			lipgloss.NewStyle().Width(podiumWidth).Align(lipgloss.Center).Render(stats),
+			block,
+		))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Bottom, columns...)
+}
+
+// renderStandings lists every player's result
+func (m ResultsModel) renderStandings() string {
+	var rows []string
+	for _, r := range m.results {
+		color := playerColor(r.Color)
+		name := r.Name
+		timeText := "DNF"
+		if r.Finished {
+			timeText = fmt.Sprintf("%.1fs", r.Time.Seconds())
+		}
+		rating := ""
+		if r.Rating > 0 {
+			rating = ratingText(r.Rating, r.RatingChange)
+		}
+		row := fmt.Sprintf("%-5s %-16s %6.1f WPM %6.1f%% %8s   +%d (%d pts)   %-11s", ordinal(r.Rank), name, r.WPM, r.Accuracy, timeText, r.Points, r.TotalPoints, rating)
+		rows = append(rows, lipgloss.NewStyle().Foreground(color).Bold(r.PlayerIndex == m.playerIndex).Render(row))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (m ResultsModel) View() string {
+	var content strings.Builder
+
+	content.WriteString("🏆 Results\n\n")
+	content.WriteString(m.renderPodium())
+	content.WriteString("\n\n")
+
+	if r, ok := m.resultFor(m.playerIndex); ok {
+		placement := fmt.Sprintf("You placed %s of %d", ordinal(r.Rank), len(m.results))
+		if !r.Finished {
+			placement = fmt.Sprintf("You did not finish (%s of %d)", ordinal(r.Rank), len(m.results))
+		}
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render(placement))
+		content.WriteString("\n")
+		if r.Rating > 0 {
+			change := lipgloss.NewStyle()
+			switch {
+			case r.RatingChange > 0:
+				change = change.Foreground(lipgloss.Color("2"))
+			case r.RatingChange < 0:
+				change = change.Foreground(lipgloss.Color("1"))
+			}
+			content.WriteString(change.Render(fmt.Sprintf("Rating %d (%+d)", r.Rating, r.RatingChange)))
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+
+	content.WriteString(m.renderStandings())
+	content.WriteString("\n\n")
+
+	switch {
+	case m.passage.Author != "" && m.passage.Source != "":
+		content.WriteString(lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("240")).
+			Render(fmt.Sprintf("Passage: %s, %s", m.passage.Author, m.passage.Source)))
+		content.WriteString("\n\n")
+	case m.passage.Source != "":
+		content.WriteString(lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("240")).
+			Render("Passage: " + m.passage.Source))
+		content.WriteString("\n\n")
+	}
+
+	var buttons []string
+	for i, btn := range m.choices {
+		buttons = append(buttons, zone.Mark(fmt.Sprintf("results-button-%d", i), btn.View()))
+	}
+	content.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, buttons[0], "   ", buttons[1]))
+	content.WriteString("\n\n")
+
+	if len(m.roster) > 0 {
+		votes := fmt.Sprintf("Rematch votes: %d/%d", m.rematchVotes(), len(m.roster))
+		if !m.isHost && m.playerIndex >= 0 {
+			votes += " • the host starts the rematch"
+		}
+		content.WriteString(votes)
+		content.WriteString("\n\n")
+	}
+
+	help := "←/h →/l move • enter select • esc lobby • q quit"
+	content.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(help))
+
+	return lipgloss.NewStyle().
+		Padding(1).
+		Align(lipgloss.Center).
+		Render(content.String())
+}
@@ -0,0 +1,101 @@
+package screens
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	zone "github.com/lrstanley/bubblezone"
+
+	"github.com/givensuman/teletyperacer/client/internal/types"
+)
+
+// ServersModel lists the server profiles the player can switch to
+type ServersModel struct {
+	profiles []types.ServerProfile
+	current  string // name of the profile in use
+	path     string // config file profiles are added to
+	cursor   int
+}
+
+func NewServers(profiles []types.ServerProfile, current, path string) ServersModel {
+	m := ServersModel{profiles: profiles, current: current, path: path}
+	for i, p := range profiles {
+		if p.Name == current {
+			m.cursor = i
+		}
+	}
+	return m
+}
+
+func (m ServersModel) Init() tea.Cmd {
+	return nil
+}
+
+// switchTo connects to the profile at index i
+func (m ServersModel) switchTo(i int) tea.Cmd {
+	name := m.profiles[i].Name
+	return func() tea.Msg { return types.SwitchServerMsg{Profile: name} }
+}
+
+func (m ServersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "k", "up":
+			m.cursor = max(m.cursor-1, 0)
+		case "j", "down":
+			m.cursor = min(m.cursor+1, len(m.profiles)-1)
+		case "enter":
+			if len(m.profiles) > 0 {
+				return m, m.switchTo(m.cursor)
+			}
+		case "esc", "q":
+			return m, func() tea.Msg { return types.ScreenChangeMsg{Screen: types.HomeScreen} }
+		}
+	case tea.MouseMsg:
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+			for i := range m.profiles {
+				if zone.Get(fmt.Sprintf("server-%d", i)).InBounds(msg) {
+					m.cursor = i
+					return m, m.switchTo(i)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m ServersModel) View() string {
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.ANSIColor(4)).
+		Padding(1, 2).
+		Width(60)
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.ANSIColor(4))
+
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Servers"), ""}
+	for i, p := range m.profiles {
+		name := p.Name
+		if name == "" {
+			name = "default"
+		}
+		row := fmt.Sprintf("%-12s  %s", name, p.Server)
+		if p.Name == m.current {
+			row += " ✓"
+		}
+		if i == m.cursor {
+			row = selectedStyle.Render("> " + row)
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, zone.Mark(fmt.Sprintf("server-%d", i), row))
+	}
+	if len(m.profiles) < 2 && m.path != "" {
+		lines = append(lines, "", helpStyle.Render("Add profiles to "+m.path))
+	}
+	lines = append(lines, "", helpStyle.Render("↑/↓ select • enter switch • esc back"))
+	return boxStyle.Render(strings.Join(lines, "\n"))
+}
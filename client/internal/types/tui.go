@@ -2,6 +2,8 @@
 // for the TUI
 package types
 
+import "time"
+
 type Screen int
 
 const (
@@ -9,6 +11,11 @@ const (
 	LobbyScreen
 	PracticeScreen
 	JoinScreen
+	RaceScreen
+	ResultsScreen
+	NicknameScreen
+	HostScreen
+	ServersScreen
 )
 
 type ScreenChangeMsg struct {
@@ -22,20 +29,54 @@ const (
 	Connected
 	ServerUnreachable
 	ClientError
-	Failed // Keep for backward compatibility
+	Disconnected   // The server stopped responding or closed the connection
+	UpdateRequired // The server no longer supports this client's protocol
+	Failed         // Keep for backward compatibility
 )
 
 type ConnectionStatusMsg struct {
-	Status ConnectionStatus
+	Status  ConnectionStatus
+	RetryAt time.Time // when the client next tries to connect, zero if it won't
 }
 
 // Room-related messages
-type CreateRoomMsg struct{}
+// CreateRoomMsg asks the server for a new room, which needs
+// Password to join unless it is empty
+type CreateRoomMsg struct {
+	Password string
+}
+
+// ServerProfile is a server the player can switch to. The
+// default server has no name.
+type ServerProfile struct {
+	Name   string
+	Server string
+}
+
+// SwitchServerMsg connects to the server of the named profile, or
+// the default server if Profile is empty
+type SwitchServerMsg struct {
+	Profile string
+}
+
+// ServerChangedMsg names the server being played on
+type ServerChangedMsg struct {
+	Name string
+}
+
+// HostRoomMsg opens the host lobby for a new room
+type HostRoomMsg struct {
+	Password string
+}
 
 type JoinRoomMsg struct {
-	Code string
+	Code     string
+	Password string
 }
 
+// LeaveRoomMsg tells the server the player has left their room
+type LeaveRoomMsg struct{}
+
 type GetRoomStateMsg struct {
 	Code string
 }
@@ -48,15 +89,266 @@ type RoomJoinedMsg struct {
 	Code string
 }
 
+// SpectateRoomMsg asks to watch a room without racing in it
+type SpectateRoomMsg struct {
+	Code     string
+	Password string
+}
+
+// SpectatingMsg confirms the player is watching a room. Passage is
+// set if a race is under way.
+type SpectatingMsg struct {
+	Code    string
+	Phase   RacePhase
+	Passage *Passage
+}
+
+// QuickMatchMsg asks the server for a public race with whoever
+// else is looking for one, or with players of a similar rating
+type QuickMatchMsg struct {
+	Ranked bool
+}
+
+// QueueStatusMsg reports on a quick-match search
+type QueueStatusMsg struct {
+	Waiting int // players searching, including this one
+	MatchIn int // seconds until a room is made for whoever is waiting
+}
+
+// ListRoomsMsg asks the server for the public rooms
+type ListRoomsMsg struct{}
+
+// RoomSummary describes a public room in the room browser
+type RoomSummary struct {
+	Code        string
+	Host        string // the host's name
+	PlayerCount int
+	Spectators  int
+	Phase       RacePhase
+	Settings    RoomSettings
+	Password    bool // joining needs a password
+}
+
+// RoomListMsg lists the public rooms, those waiting for players first
+type RoomListMsg struct {
+	Rooms []RoomSummary
+}
+
 type PlayerJoinedMsg struct {
 	PlayerIndex int
 }
 
+// Player is one entry in a room's roster. Index identifies the
+// player in every other message and is never reused within a room.
+type Player struct {
+	Index   int
+	Name    string
+	Color   int
+	Host    bool
+	Ready   bool
+	Rematch bool // voted to race again after the last race
+	Points  int  // earned over every race played in the room
+
+	Rating       int
+	RatingChange int // from the player's last race in the room
+}
+
 type RoomStateMsg struct {
 	Code        string
 	PlayerCount int
 	YourIndex   int
 	Version     int
+	Phase       RacePhase
+	Players     []Player
+	Locked      bool // whether the room refuses new players
+	AutoStart   AutoStart
+	Settings    RoomSettings
+	StartingIn  int // seconds until an automatic start, zero if none is pending
+	Rounds      int // races completed in the room
+	Spectators  int
+	Spectating  bool // YourIndex is -1 while spectating
+}
+
+// RoomSettings are the host's choices for a room. Empty Length and
+// Language match any passage, and a zero Countdown uses the server's
+// default.
+type RoomSettings struct {
+	MaxPlayers  int
+	Length      string
+	Language    string
+	Punctuation bool
+	Numbers     bool
+	Countdown   int // seconds
+	Public      bool
+}
+
+type SetSettingsMsg struct {
+	Settings RoomSettings
+}
+
+// AutoStart describes when a room starts racing without the host.
+// MinPlayers of zero disables starting on player count.
+type AutoStart struct {
+	WhenAllReady bool
+	MinPlayers   int
+	Delay        int // seconds between the trigger and the race
+}
+
+type SetReadyMsg struct {
+	Ready bool
+}
+
+type SetAutoStartMsg struct {
+	AutoStart AutoStart
+}
+
+// Host controls, which the server only accepts from the room's host
+type KickPlayerMsg struct {
+	PlayerIndex int
+}
+
+type LockRoomMsg struct {
+	Locked bool
+}
+
+type TransferHostMsg struct {
+	PlayerIndex int
+}
+
+type VoteRematchMsg struct {
+	Vote bool
+}
+
+// AcceptRematchMsg is sent by the host to take everyone back to the lobby
+type AcceptRematchMsg struct{}
+
+// NicknameChangedMsg is sent when the player picks a new nickname
+type NicknameChangedMsg struct {
+	Name string
+}
+
+// ChatMessage is one line of a room's chat, as stamped by the server
+type ChatMessage struct {
+	PlayerIndex int
+	Name        string
+	Color       int
+	Text        string
+	SentAt      time.Time
+}
+
+// SendChatMsg sends a chat message to the player's room
+type SendChatMsg struct {
+	Text string
+}
+
+type ChatReceivedMsg struct {
+	Message ChatMessage
+}
+
+// ChatHistoryMsg carries the room's recent chat on arrival, oldest first
+type ChatHistoryMsg struct {
+	Messages []ChatMessage
+}
+
+// RacePhase mirrors the server's race lifecycle
+type RacePhase int
+
+const (
+	PhaseWaiting RacePhase = iota
+	PhaseCountdown
+	PhaseRacing
+	PhaseFinished
+)
+
+// Race-related messages
+type StartRaceMsg struct{}
+
+type FinishRaceMsg struct{}
+
+// Passage is the text a race is run on
+type Passage struct {
+	ID     string
+	Text   string
+	Source string
+	Author string
+}
+
+type RacePhaseMsg struct {
+	Phase     RacePhase
+	Countdown int      // seconds until racing, set during PhaseCountdown
+	Passage   *Passage // text to race on, set during PhaseCountdown
+}
+
+type PlayerFinishedMsg struct {
+	PlayerIndex int
+	Place       int
+}
+
+// PlayerProgressMsg reports another racer's live progress
+type PlayerProgressMsg struct {
+	PlayerIndex int
+	Position    int
+	WPM         float64
+	Errors      int
+}
+
+// RaceResult is one player's standing as decided by the server
+type RaceResult struct {
+	PlayerIndex int
+	Name        string
+	Color       int
+	Rank        int
+	WPM         float64
+	Accuracy    float64
+	Time        time.Duration
+	Finished    bool
+	Points      int // earned this race
+	TotalPoints int // earned over every race played in the room
+
+	Rating       int // after this race
+	RatingChange int
+}
+
+type RaceResultsMsg struct {
+	Passage Passage
+	Results []RaceResult
+}
+
+// WelcomeMsg is the server's answer to the client's hello.
+// Capabilities lists the features enabled for this connection.
+type WelcomeMsg struct {
+	ProtocolVersion    int
+	MinProtocolVersion int
+	ServerVersion      string
+	Capabilities       []string
+	Languages          []string // passage languages rooms can choose from
+}
+
+// SessionMsg carries the resumable session issued by the server
+type SessionMsg struct {
+	ClientID string
+	Token    string
+}
+
+// SessionExpiredMsg reports that the server no longer has the
+// session the client tried to resume
+type SessionExpiredMsg struct{}
+
+// SessionResumedMsg describes the room and race a resumed
+// session returns to. Code is empty if the session had no room.
+type SessionResumedMsg struct {
+	Code        string
+	PlayerIndex int
+	Phase       RacePhase
+	Passage     *Passage
+	Position    int
+	Finished    bool
+}
+
+// ServerErrorMsg carries an error the server sent in
+// response to a rejected request
+type ServerErrorMsg struct {
+	Message string
 }
 
 type CopyCodeMsg struct {
@@ -71,6 +363,17 @@ type ClipboardSuccessMsg struct {
 	Message string
 }
 
-type RoomJoinFailedMsg struct {
+// RoomClosedMsg reports that the server closed the room the
+// client was in, or removed the client from it. Reason is a
+// machine-readable code such as "idle" or "kicked".
+type RoomClosedMsg struct {
+	Code   string
 	Reason string
 }
+
+// RoomJoinFailedMsg reports why the server refused a join.
+// Reason is a machine-readable code such as "roomFull".
+type RoomJoinFailedMsg struct {
+	Reason  string
+	Message string
+}
@@ -1,21 +1,84 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
 	"github.com/charmbracelet/bubbletea"
 	zone "github.com/lrstanley/bubblezone"
 
+	"github.com/givensuman/teletyperacer/client/internal/config"
 	"github.com/givensuman/teletyperacer/client/internal/tui"
 )
 
+// Environment variables that stand in for flags
+const (
+	envServer      = "TELETYPERACER_SERVER"
+	envProfile     = "TELETYPERACER_PROFILE"
+	envNickname    = "TELETYPERACER_NICKNAME"
+	envConfig      = "TELETYPERACER_CONFIG"
+	envNoMouse     = "TELETYPERACER_NO_MOUSE"
+	envNoAltScreen = "TELETYPERACER_NO_ALT_SCREEN"
+)
+
+// envBool reads a boolean environment variable, false if unset
+func envBool(key string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(key))
+	return b
+}
+
 // https://github.com/givensuman/teletyperacer
 func main() {
+	// Flags take precedence over the environment, which takes
+	// precedence over the config file
+	server := flag.String("server", os.Getenv(envServer), "server to play on, such as example.com:3000 [$"+envServer+"]")
+	profile := flag.String("profile", os.Getenv(envProfile), "server profile from the config file to play on [$"+envProfile+"]")
+	nickname := flag.String("nickname", os.Getenv(envNickname), "nickname for this run [$"+envNickname+"]")
+	configFile := flag.String("config", os.Getenv(envConfig), "config file to use instead of the default [$"+envConfig+"]")
+	noMouse := flag.Bool("no-mouse", envBool(envNoMouse), "leave the mouse to the terminal [$"+envNoMouse+"]")
+	noAltScreen := flag.Bool("no-alt-screen", envBool(envNoAltScreen), "draw below the prompt instead of taking over the terminal [$"+envNoAltScreen+"]")
+	flag.Parse()
+
+	config.File = *configFile
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring unreadable config file: %v\n", err)
+	} else if cfg.PlayerID == "" {
+		// Without a saved ID the player's rating would reset every run
+		if cfg.PlayerID, err = config.NewPlayerID(); err != nil {
+			fmt.Fprintf(os.Stderr, "Playing without a player ID, ratings will not be kept: %v\n", err)
+		} else {
+			config.Update(func(c *config.Config) { c.PlayerID = cfg.PlayerID })
+		}
+	}
+
+	opts := root.Options{Nickname: *nickname}
+	if *server != "" {
+		opts.Server, err = config.NormalizeServer(*server)
+	} else {
+		opts.Profile = cfg.Profile
+		if *profile != "" {
+			opts.Profile = *profile
+		}
+		opts.Server, err = cfg.ServerFor(opts.Profile)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid server: %v\n", err)
+		os.Exit(2)
+	}
+
 	zone.NewGlobal()
 
-	p := tea.NewProgram(
-		root.New(),
-		tea.WithAltScreen(),
-		tea.WithMouseAllMotion(),
-	)
+	options := []tea.ProgramOption{}
+	if !*noAltScreen && !cfg.NoAltScreen {
+		options = append(options, tea.WithAltScreen())
+	}
+	if !*noMouse && !cfg.NoMouse {
+		options = append(options, tea.WithMouseAllMotion())
+	}
+	p := tea.NewProgram(root.New(cfg, opts), options...)
 
 	if _, err := p.Run(); err != nil {
 		panic(err)
@@ -0,0 +1,3 @@
+module github.com/givensuman/teletyperacer/protocol
+
+go 1.25.2
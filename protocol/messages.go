@@ -0,0 +1,633 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Race phases, as sent in phase fields
+const (
+	PhaseWaiting   = "waiting"
+	PhaseCountdown = "countdown"
+	PhaseRacing    = "racing"
+	PhaseFinished  = "finished"
+)
+
+// Capabilities peers may advertise during the handshake
+const (
+	CapabilityHeartbeat = "heartbeat"
+	CapabilityProgress  = "progress"
+	CapabilityResume    = "resume"
+)
+
+// MaxNameLength is the longest nickname, in characters,
+// a player may use
+const MaxNameLength = 16
+
+// Bounds on room settings
+const (
+	MinPlayers   = 2
+	MaxPlayers   = 10
+	MinCountdown = 3 // seconds
+	MaxCountdown = 30
+)
+
+// MaxChatLength is the longest chat message, in characters
+const MaxChatLength = 200
+
+// MaxPasswordLength is the longest room password, in characters
+const MaxPasswordLength = 64
+
+// Bounds on the auto-start countdown, in seconds
+const (
+	MinAutoStartDelay = 3
+	MaxAutoStartDelay = 60
+)
+
+// Reasons attached to errors the client can act on
+const (
+	ReasonRoomNotFound   = "roomNotFound"
+	ReasonRoomFull       = "roomFull"
+	ReasonRoomLocked     = "roomLocked"
+	ReasonRaceInProgress = "raceInProgress"
+	ReasonSessionExpired = "sessionExpired"
+	ReasonUpdateRequired = "updateRequired"
+	ReasonInvalidMessage = "invalidMessage"
+	ReasonNotHost        = "notHost"
+	ReasonWrongPassword  = "wrongPassword" // also sent when a password is missing
+)
+
+// Reasons the server may close a room, or remove a client from one
+const (
+	ClosedIdle   = "idle"
+	ClosedKicked = "kicked"
+	ClosedEmpty  = "empty" // sent to spectators when the last player leaves
+)
+
+func init() {
+	register(
+		HelloRequest{},
+		WelcomeResponse{},
+		SessionResponse{},
+		ResumeRequest{},
+		ResumedResponse{},
+		ErrorResponse{},
+
+		CreateRoomRequest{},
+		RoomCreatedResponse{},
+		JoinRoomRequest{},
+		RoomJoinedResponse{},
+		LeaveRoomRequest{},
+		SpectateRoomRequest{},
+		SpectatingResponse{},
+		QuickMatchRequest{},
+		QueueStatusResponse{},
+		ListRoomsRequest{},
+		RoomListResponse{},
+		PlayerJoinedResponse{},
+		GetRoomStateRequest{},
+		RoomStateResponse{},
+		RoomClosedResponse{},
+		KickPlayerRequest{},
+		LockRoomRequest{},
+		TransferHostRequest{},
+		SetReadyRequest{},
+		SetSettingsRequest{},
+		SetAutoStartRequest{},
+		ChatRequest{},
+		ChatResponse{},
+		ChatHistoryResponse{},
+
+		StartRaceRequest{},
+		RacePhaseResponse{},
+		ProgressRequest{},
+		PlayerProgressResponse{},
+		FinishRaceRequest{},
+		PlayerFinishedResponse{},
+		RaceResultsResponse{},
+		VoteRematchRequest{},
+		AcceptRematchRequest{},
+	)
+}
+
+// Connection
+
+// HelloRequest opens every connection. PlayerID is a random ID the
+// client keeps between runs, so its rating follows it; clients
+// without one are rated for the connection only.
+type HelloRequest struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Version         string   `json:"version"`
+	Capabilities    []string `json:"capabilities"`
+	PlayerID        string   `json:"playerId,omitempty"`
+}
+
+func (HelloRequest) MessageType() string { return "hello" }
+
+// WelcomeResponse answers the client's hello. ProtocolVersion is
+// the version spoken on the connection, the lower of the client's
+// and the server's.
+type WelcomeResponse struct {
+	ProtocolVersion    int      `json:"protocolVersion"`
+	MinProtocolVersion int      `json:"minProtocolVersion"`
+	Version            string   `json:"version"`
+	Capabilities       []string `json:"capabilities"` // enabled for this connection
+	Languages          []string `json:"languages"`    // passage languages rooms can choose from
+}
+
+func (WelcomeResponse) MessageType() string { return "welcome" }
+
+type SessionResponse struct {
+	ClientID string `json:"clientId"`
+	Token    string `json:"token"`
+}
+
+func (SessionResponse) MessageType() string { return "session" }
+
+type ResumeRequest struct {
+	Token string `json:"token"`
+}
+
+func (ResumeRequest) MessageType() string { return "resume" }
+
+func (r ResumeRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+type ResumedResponse struct {
+	ClientID    string           `json:"clientId"`
+	Code        string           `json:"code,omitempty"`
+	PlayerIndex int              `json:"playerIndex"`
+	Phase       string           `json:"phase,omitempty"`
+	Passage     *PassageResponse `json:"passage,omitempty"`
+	Position    int              `json:"position"`
+	Finished    bool             `json:"finished"`
+}
+
+func (ResumedResponse) MessageType() string { return "resumed" }
+
+type ErrorResponse struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func (ErrorResponse) MessageType() string { return "error" }
+
+// Rooms
+
+// CreateRoomRequest opens a room. Players must give Password to
+// join or watch it, unless it is empty.
+type CreateRoomRequest struct {
+	Name     string `json:"name,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func (CreateRoomRequest) MessageType() string { return "createRoom" }
+
+func (r CreateRoomRequest) Validate() error {
+	if err := validatePassword(r.Password); err != nil {
+		return err
+	}
+	return validateName(r.Name)
+}
+
+type RoomCreatedResponse struct {
+	Code string `json:"code"`
+}
+
+func (RoomCreatedResponse) MessageType() string { return "roomCreated" }
+
+type JoinRoomRequest struct {
+	Code     string `json:"code"`
+	Name     string `json:"name,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func (JoinRoomRequest) MessageType() string { return "joinRoom" }
+
+func (r JoinRoomRequest) Validate() error {
+	if r.Code == "" {
+		return errors.New("room code is required")
+	}
+	if err := validatePassword(r.Password); err != nil {
+		return err
+	}
+	return validateName(r.Name)
+}
+
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) > MaxPasswordLength {
+		return fmt.Errorf("password is longer than %d characters", MaxPasswordLength)
+	}
+	return nil
+}
+
+// validateName checks a requested nickname. Empty names are allowed
+// and replaced with a default by the server.
+func validateName(name string) error {
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return fmt.Errorf("name is longer than %d characters", MaxNameLength)
+	}
+	return nil
+}
+
+type RoomJoinedResponse struct {
+	Code string `json:"code"`
+}
+
+func (RoomJoinedResponse) MessageType() string { return "roomJoined" }
+
+type LeaveRoomRequest struct{}
+
+func (LeaveRoomRequest) MessageType() string { return "leaveRoom" }
+
+// SpectateRoomRequest watches a room without taking a player slot.
+// Spectators receive everything broadcast to the room, but cannot
+// race or chat. Joining a room mid-race makes the client a spectator
+// too, and a spectator can later join the room to race.
+type SpectateRoomRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password,omitempty"`
+}
+
+func (SpectateRoomRequest) MessageType() string { return "spectateRoom" }
+
+func (r SpectateRoomRequest) Validate() error {
+	if r.Code == "" {
+		return errors.New("room code is required")
+	}
+	return validatePassword(r.Password)
+}
+
+// SpectatingResponse confirms the client is watching a room. Passage
+// is set if a race is under way.
+type SpectatingResponse struct {
+	Code    string           `json:"code"`
+	Phase   string           `json:"phase"`
+	Passage *PassageResponse `json:"passage,omitempty"`
+}
+
+func (SpectatingResponse) MessageType() string { return "spectating" }
+
+// ListRoomsRequest asks for the public rooms, also served over
+// HTTP at /api/rooms
+type ListRoomsRequest struct{}
+
+func (ListRoomsRequest) MessageType() string { return "listRooms" }
+
+// RoomSummary describes a public room in the room browser
+type RoomSummary struct {
+	Code        string       `json:"code"`
+	Host        string       `json:"host"` // the host's name
+	PlayerCount int          `json:"playerCount"`
+	Spectators  int          `json:"spectators"`
+	Phase       string       `json:"phase"`
+	Settings    RoomSettings `json:"settings"`
+	Password    bool         `json:"password"` // joining needs a password
+}
+
+// RoomListResponse lists the public rooms that are not locked,
+// those waiting for players first
+type RoomListResponse struct {
+	Rooms []RoomSummary `json:"rooms"`
+}
+
+func (RoomListResponse) MessageType() string { return "roomList" }
+
+// QuickMatchRequest queues the client for a public room with whoever
+// else is looking for a race, or with players of a similar rating if
+// Ranked is set. Leaving the room also leaves the queue.
+type QuickMatchRequest struct {
+	Name   string `json:"name,omitempty"`
+	Ranked bool   `json:"ranked,omitempty"`
+}
+
+func (QuickMatchRequest) MessageType() string { return "quickMatch" }
+
+func (r QuickMatchRequest) Validate() error {
+	return validateName(r.Name)
+}
+
+// QueueStatusResponse tells queued clients how their search is going.
+// Once matched they receive roomJoined as if they had joined by code.
+type QueueStatusResponse struct {
+	Waiting int `json:"waiting"` // players in the queue, including this one
+	MatchIn int `json:"matchIn"` // seconds until a room is made for whoever is waiting
+}
+
+func (QueueStatusResponse) MessageType() string { return "queueStatus" }
+
+type PlayerJoinedResponse struct {
+	PlayerIndex int `json:"playerIndex"`
+}
+
+func (PlayerJoinedResponse) MessageType() string { return "playerJoined" }
+
+type GetRoomStateRequest struct {
+	Code string `json:"code"`
+}
+
+func (GetRoomStateRequest) MessageType() string { return "getRoomState" }
+
+// Player is one entry in a room's roster. Index identifies the
+// player in every other message and is never reused within a room.
+type Player struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	Color   int    `json:"color"`
+	Host    bool   `json:"host"`
+	Ready   bool   `json:"ready"`
+	Rematch bool   `json:"rematch"` // voted for a rematch of the last race
+	Points  int    `json:"points"`  // earned over every race played in the room
+
+	Rating       int `json:"rating"`
+	RatingChange int `json:"ratingChange"` // from the player's last race in the room
+}
+
+type RoomStateResponse struct {
+	Code        string       `json:"code"`
+	PlayerCount int          `json:"playerCount"`
+	YourIndex   int          `json:"yourIndex"`
+	Version     int          `json:"version"`
+	Phase       string       `json:"phase"`
+	Players     []Player     `json:"players"`
+	Locked      bool         `json:"locked"`
+	AutoStart   AutoStart    `json:"autoStart"`
+	Settings    RoomSettings `json:"settings"`
+	StartingIn  int          `json:"startingIn"` // seconds until an automatic start, if one is pending
+	Rounds      int          `json:"rounds"`     // races completed in the room
+	Spectators  int          `json:"spectators"`
+	Spectating  bool         `json:"spectating"` // YourIndex is -1 for spectators
+}
+
+func (RoomStateResponse) MessageType() string { return "roomState" }
+
+type RoomClosedResponse struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+func (RoomClosedResponse) MessageType() string { return "roomClosed" }
+
+// Host controls
+
+type KickPlayerRequest struct {
+	PlayerIndex int `json:"playerIndex"`
+}
+
+func (KickPlayerRequest) MessageType() string { return "kickPlayer" }
+
+func (r KickPlayerRequest) Validate() error {
+	return validatePlayerIndex(r.PlayerIndex)
+}
+
+type LockRoomRequest struct {
+	Locked bool `json:"locked"`
+}
+
+func (LockRoomRequest) MessageType() string { return "lockRoom" }
+
+type TransferHostRequest struct {
+	PlayerIndex int `json:"playerIndex"`
+}
+
+func (TransferHostRequest) MessageType() string { return "transferHost" }
+
+func (r TransferHostRequest) Validate() error {
+	return validatePlayerIndex(r.PlayerIndex)
+}
+
+// AutoStart describes when a room starts racing without the host.
+// MinPlayers of zero disables starting on player count.
+type AutoStart struct {
+	WhenAllReady bool `json:"whenAllReady"`
+	MinPlayers   int  `json:"minPlayers"`
+	Delay        int  `json:"delay"` // seconds between the trigger and the race
+}
+
+type SetAutoStartRequest struct {
+	AutoStart AutoStart `json:"autoStart"`
+}
+
+func (SetAutoStartRequest) MessageType() string { return "setAutoStart" }
+
+func (r SetAutoStartRequest) Validate() error {
+	if r.AutoStart.MinPlayers < 0 {
+		return errors.New("minimum players cannot be negative")
+	}
+	if r.AutoStart.Delay < MinAutoStartDelay || r.AutoStart.Delay > MaxAutoStartDelay {
+		return fmt.Errorf("auto-start delay must be between %d and %d seconds", MinAutoStartDelay, MaxAutoStartDelay)
+	}
+	return nil
+}
+
+// RoomSettings are the host's choices for a room. Empty Length and
+// Language match any passage, and a zero Countdown uses the server's
+// default.
+type RoomSettings struct {
+	MaxPlayers  int    `json:"maxPlayers"`
+	Length      string `json:"length,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Punctuation bool   `json:"punctuation"`
+	Numbers     bool   `json:"numbers"`
+	Countdown   int    `json:"countdown"` // seconds
+	Public      bool   `json:"public"`
+}
+
+func (s RoomSettings) Validate() error {
+	if s.MaxPlayers < MinPlayers || s.MaxPlayers > MaxPlayers {
+		return fmt.Errorf("max players must be between %d and %d", MinPlayers, MaxPlayers)
+	}
+	if err := validateLength(s.Length); err != nil {
+		return err
+	}
+	if s.Countdown != 0 && (s.Countdown < MinCountdown || s.Countdown > MaxCountdown) {
+		return fmt.Errorf("countdown must be between %d and %d seconds", MinCountdown, MaxCountdown)
+	}
+	return nil
+}
+
+type SetSettingsRequest struct {
+	Settings RoomSettings `json:"settings"`
+}
+
+func (SetSettingsRequest) MessageType() string { return "setSettings" }
+
+func (r SetSettingsRequest) Validate() error {
+	return r.Settings.Validate()
+}
+
+type SetReadyRequest struct {
+	Ready bool `json:"ready"`
+}
+
+func (SetReadyRequest) MessageType() string { return "setReady" }
+
+func validatePlayerIndex(index int) error {
+	if index < 0 {
+		return errors.New("player index cannot be negative")
+	}
+	return nil
+}
+
+// Chat
+
+type ChatRequest struct {
+	Text string `json:"text"`
+}
+
+func (ChatRequest) MessageType() string { return "chat" }
+
+func (r ChatRequest) Validate() error {
+	if strings.TrimSpace(r.Text) == "" {
+		return errors.New("message is empty")
+	}
+	if utf8.RuneCountInString(r.Text) > MaxChatLength {
+		return fmt.Errorf("message is longer than %d characters", MaxChatLength)
+	}
+	return nil
+}
+
+// ChatMessage is one line of a room's chat, as stamped by the server
+type ChatMessage struct {
+	PlayerIndex int    `json:"playerIndex"`
+	Name        string `json:"name"`
+	Color       int    `json:"color"`
+	Text        string `json:"text"`
+	SentAt      int64  `json:"sentAt"` // unix milliseconds
+}
+
+type ChatResponse struct {
+	Code    string      `json:"code"`
+	Message ChatMessage `json:"message"`
+}
+
+func (ChatResponse) MessageType() string { return "chatMessage" }
+
+// ChatHistoryResponse carries a room's recent chat to a player who
+// has just arrived, oldest first
+type ChatHistoryResponse struct {
+	Code     string        `json:"code"`
+	Messages []ChatMessage `json:"messages"`
+}
+
+func (ChatHistoryResponse) MessageType() string { return "chatHistory" }
+
+// Races
+
+type StartRaceRequest struct {
+	Length     string `json:"length,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+}
+
+func (StartRaceRequest) MessageType() string { return "startRace" }
+
+func (r StartRaceRequest) Validate() error {
+	if err := validateLength(r.Length); err != nil {
+		return err
+	}
+	switch r.Difficulty {
+	case "", "easy", "normal", "hard":
+	default:
+		return errors.New("unknown difficulty " + r.Difficulty)
+	}
+	return nil
+}
+
+func validateLength(length string) error {
+	switch length {
+	case "", "short", "medium", "long":
+		return nil
+	}
+	return errors.New("unknown passage length " + length)
+}
+
+type PassageResponse struct {
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	Source string `json:"source"`
+	Author string `json:"author"`
+}
+
+type RacePhaseResponse struct {
+	Code      string           `json:"code"`
+	Phase     string           `json:"phase"`
+	Countdown int              `json:"countdown,omitempty"`
+	Passage   *PassageResponse `json:"passage,omitempty"`
+}
+
+func (RacePhaseResponse) MessageType() string { return "racePhase" }
+
+type ProgressRequest struct {
+	Position int     `json:"position"`
+	WPM      float64 `json:"wpm"`
+	Errors   int     `json:"errors"`
+}
+
+func (ProgressRequest) MessageType() string { return "progress" }
+
+func (r ProgressRequest) Validate() error {
+	if r.Position < 0 || r.Errors < 0 || r.WPM < 0 {
+		return errors.New("progress cannot be negative")
+	}
+	return nil
+}
+
+type PlayerProgressResponse struct {
+	PlayerIndex int     `json:"playerIndex"`
+	Position    int     `json:"position"`
+	WPM         float64 `json:"wpm"`
+	Errors      int     `json:"errors"`
+}
+
+func (PlayerProgressResponse) MessageType() string { return "playerProgress" }
+
+type FinishRaceRequest struct{}
+
+func (FinishRaceRequest) MessageType() string { return "finishRace" }
+
+type PlayerFinishedResponse struct {
+	PlayerIndex int `json:"playerIndex"`
+	Place       int `json:"place"`
+}
+
+func (PlayerFinishedResponse) MessageType() string { return "playerFinished" }
+
+type RaceResult struct {
+	PlayerIndex int     `json:"playerIndex"`
+	Name        string  `json:"name"`
+	Color       int     `json:"color"`
+	Rank        int     `json:"rank"`
+	WPM         float64 `json:"wpm"`
+	Accuracy    float64 `json:"accuracy"`
+	TimeMs      int64   `json:"timeMs"`
+	Finished    bool    `json:"finished"`
+	Points      int     `json:"points"`      // earned in this race
+	TotalPoints int     `json:"totalPoints"` // earned over every race played in the room
+
+	Rating       int `json:"rating"` // after this race
+	RatingChange int `json:"ratingChange"`
+}
+
+type RaceResultsResponse struct {
+	Code    string          `json:"code"`
+	Passage PassageResponse `json:"passage"`
+	Results []RaceResult    `json:"results"`
+}
+
+func (RaceResultsResponse) MessageType() string { return "raceResults" }
+
+type VoteRematchRequest struct {
+	Vote bool `json:"vote"`
+}
+
+func (VoteRematchRequest) MessageType() string { return "voteRematch" }
+
+type AcceptRematchRequest struct{}
+
+func (AcceptRematchRequest) MessageType() string { return "acceptRematch" }
@@ -0,0 +1,109 @@
+// Package protocol defines the messages exchanged between
+// the teletyperacer client and server
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Version is the wire protocol version described by this package.
+// Bump it whenever a change would break existing peers.
+const Version = 1
+
+// MinVersion is the oldest protocol version servers still accept
+const MinVersion = 1
+
+var (
+	ErrUnknownType = errors.New("unknown message type")
+	ErrInvalid     = errors.New("invalid message")
+)
+
+// Message is implemented by every payload that can be sent over
+// the wire. MessageType names the payload in its envelope.
+type Message interface {
+	MessageType() string
+}
+
+// Validator is implemented by messages that can check their own
+// fields. Decode rejects messages that fail validation.
+type Validator interface {
+	Validate() error
+}
+
+// Envelope is the JSON frame every message travels in
+type Envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// registry maps message type names to their payload types
+var registry = make(map[string]reflect.Type)
+
+// register makes a message decodable. Registering the same type
+// name twice is a programming error and panics.
+func register(msgs ...Message) {
+	for _, msg := range msgs {
+		name := msg.MessageType()
+		if _, exists := registry[name]; exists {
+			panic("protocol: message type registered twice: " + name)
+		}
+		registry[name] = reflect.TypeOf(msg)
+	}
+}
+
+// Types returns the names of every registered message type, sorted
+func Types() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encode wraps msg in an envelope and marshals it
+func Encode(msg Message) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{Type: msg.MessageType(), Data: data})
+}
+
+// Decode unmarshals an envelope and its payload. The returned message
+// is a value of the registered type, so callers can switch on it.
+// Errors wrap ErrUnknownType or ErrInvalid.
+func Decode(data []byte) (Message, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return env.Decode()
+}
+
+// Decode unmarshals and validates the envelope's payload
+func (e Envelope) Decode() (Message, error) {
+	t, exists := registry[e.Type]
+	if !exists {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, e.Type)
+	}
+
+	payload := reflect.New(t)
+	if len(e.Data) > 0 && string(e.Data) != "null" {
+		if err := json.Unmarshal(e.Data, payload.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, e.Type, err)
+		}
+	}
+
+	msg := payload.Elem().Interface().(Message)
+	if v, ok := msg.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, e.Type, err)
+		}
+	}
+	return msg, nil
+}
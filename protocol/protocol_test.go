@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// samples holds a populated value of every registered message
+var samples = []Message{
+	HelloRequest{ProtocolVersion: Version, Version: "v1.0.0", Capabilities: []string{CapabilityResume}, PlayerID: "3f2a9c"},
+	WelcomeResponse{ProtocolVersion: Version, MinProtocolVersion: MinVersion, Version: "dev", Capabilities: []string{CapabilityProgress}, Languages: []string{"en"}},
+	SessionResponse{ClientID: "c1", Token: "abc"},
+	ResumeRequest{Token: "abc"},
+	ResumedResponse{ClientID: "c1", Code: "ABC123", PlayerIndex: 1, Phase: PhaseRacing, Passage: &PassageResponse{ID: "p", Text: "hi"}, Position: 3},
+	ErrorResponse{Message: "room is full", Reason: ReasonRoomFull},
+	CreateRoomRequest{Name: "Ada", Password: "hunter2"},
+	RoomCreatedResponse{Code: "ABC123"},
+	JoinRoomRequest{Code: "ABC123", Name: "Grace", Password: "hunter2"},
+	RoomJoinedResponse{Code: "ABC123"},
+	LeaveRoomRequest{},
+	SpectateRoomRequest{Code: "ABC123", Password: "hunter2"},
+	SpectatingResponse{Code: "ABC123", Phase: "racing", Passage: &PassageResponse{ID: "p", Text: "Go."}},
+	QuickMatchRequest{Name: "Ada", Ranked: true},
+	QueueStatusResponse{Waiting: 1, MatchIn: 12},
+	ListRoomsRequest{},
+	RoomListResponse{Rooms: []RoomSummary{{Code: "ABC123", Host: "Ada", PlayerCount: 2, Spectators: 1, Phase: PhaseWaiting, Settings: RoomSettings{MaxPlayers: 4, Public: true}, Password: true}}},
+	PlayerJoinedResponse{PlayerIndex: 2},
+	GetRoomStateRequest{Code: "ABC123"},
+	RoomStateResponse{Code: "ABC123", PlayerCount: 2, YourIndex: 1, Version: 4, Phase: PhaseWaiting, Players: []Player{
+		{Index: 0, Name: "Ada", Color: 0, Host: true, Points: 3},
+		{Index: 1, Name: "Grace", Color: 1, Ready: true, Rematch: true, Points: 5},
+	}, Locked: true, AutoStart: AutoStart{WhenAllReady: true, MinPlayers: 4, Delay: 10}, Settings: RoomSettings{MaxPlayers: 6, Length: "short", Language: "en", Numbers: true, Countdown: 5, Public: true}, StartingIn: 7, Rounds: 2},
+	RoomClosedResponse{Code: "ABC123", Reason: ClosedIdle},
+	KickPlayerRequest{PlayerIndex: 1},
+	LockRoomRequest{Locked: true},
+	TransferHostRequest{PlayerIndex: 1},
+	SetReadyRequest{Ready: true},
+	SetSettingsRequest{Settings: RoomSettings{MaxPlayers: 4, Punctuation: true}},
+	SetAutoStartRequest{AutoStart: AutoStart{WhenAllReady: true, Delay: 5}},
+	ChatRequest{Text: "gl hf"},
+	ChatResponse{Code: "ABC123", Message: ChatMessage{PlayerIndex: 1, Name: "Grace", Color: 1, Text: "gl hf", SentAt: 1700000000000}},
+	ChatHistoryResponse{Code: "ABC123", Messages: []ChatMessage{{PlayerIndex: 0, Name: "Ada", Text: "hi", SentAt: 1700000000000}}},
+	StartRaceRequest{Length: "short", Difficulty: "easy"},
+	RacePhaseResponse{Code: "ABC123", Phase: PhaseCountdown, Countdown: 3, Passage: &PassageResponse{ID: "p", Text: "hi", Source: "s", Author: "a"}},
+	ProgressRequest{Position: 10, WPM: 72.5, Errors: 1},
+	PlayerProgressResponse{PlayerIndex: 1, Position: 10, WPM: 72.5, Errors: 1},
+	FinishRaceRequest{},
+	PlayerFinishedResponse{PlayerIndex: 1, Place: 1},
+	RaceResultsResponse{Code: "ABC123", Passage: PassageResponse{ID: "p"}, Results: []RaceResult{{PlayerIndex: 1, Name: "Grace", Color: 1, Rank: 1, WPM: 80, Accuracy: 99, TimeMs: 12000, Finished: true, Points: 2, TotalPoints: 5, Rating: 1216, RatingChange: 16}}},
+	VoteRematchRequest{Vote: true},
+	AcceptRematchRequest{},
+}
+
+func TestRoundTrip(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, msg := range samples {
+		data, err := Encode(msg)
+		if err != nil {
+			t.Fatalf("encode %s: %v", msg.MessageType(), err)
+		}
+		decoded, err := Decode(data)
+		if err != nil {
+			t.Fatalf("decode %s: %v", msg.MessageType(), err)
+		}
+		if !reflect.DeepEqual(decoded, msg) {
+			t.Fatalf("%s did not survive a round trip: got %#v, want %#v", msg.MessageType(), decoded, msg)
+		}
+		covered[msg.MessageType()] = true
+	}
+
+	// Every registered message needs a sample above
+	for _, name := range Types() {
+		if !covered[name] {
+			t.Errorf("no round-trip sample for %q", name)
+		}
+	}
+}
+
+func TestDecodeWithoutData(t *testing.T) {
+	msg, err := Decode([]byte(`{"type":"createRoom"}`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := msg.(CreateRoomRequest); !ok {
+		t.Fatalf("expected CreateRoomRequest, got %T", msg)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		want error
+	}{
+		"malformed envelope": {`{"type":`, ErrInvalid},
+		"unknown type":       {`{"type":"teleport"}`, ErrUnknownType},
+		"wrong field type":   {`{"type":"joinRoom","data":{"code":7}}`, ErrInvalid},
+		"missing room code":  {`{"type":"joinRoom","data":{}}`, ErrInvalid},
+		"long name":          {`{"type":"createRoom","data":{"name":"abcdefghijklmnopq"}}`, ErrInvalid},
+		"long password":      {`{"type":"joinRoom","data":{"code":"ABC123","password":"` + strings.Repeat("x", MaxPasswordLength+1) + `"}}`, ErrInvalid},
+		"negative progress":  {`{"type":"progress","data":{"position":-1}}`, ErrInvalid},
+		"unknown length":     {`{"type":"startRace","data":{"length":"epic"}}`, ErrInvalid},
+		"missing token":      {`{"type":"resume","data":{"token":""}}`, ErrInvalid},
+		"negative kick":      {`{"type":"kickPlayer","data":{"playerIndex":-1}}`, ErrInvalid},
+		"short auto-start":   {`{"type":"setAutoStart","data":{"autoStart":{"delay":1}}}`, ErrInvalid},
+		"blank chat":         {`{"type":"chat","data":{"text":"  "}}`, ErrInvalid},
+		"huge room":          {`{"type":"setSettings","data":{"settings":{"maxPlayers":11}}}`, ErrInvalid},
+	}
+	for name, tt := range tests {
+		if _, err := Decode([]byte(tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", name, tt.want, err)
+		}
+	}
+}
@@ -6,3 +6,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/websocket v1.5.3
 )
+
+require github.com/givensuman/teletyperacer/protocol v0.0.0
+
+replace github.com/givensuman/teletyperacer/protocol => ../protocol
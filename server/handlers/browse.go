@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// PublicRooms lists the public rooms that are not locked. Rooms
+// waiting for players come first, fullest first.
+func (rm *RoomManager) PublicRooms() []protocol.RoomSummary {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	rooms := make([]protocol.RoomSummary, 0)
+	for code, room := range rm.rooms {
+		if !room.settings.Public || room.locked {
+			continue
+		}
+		summary := protocol.RoomSummary{
+			Code:        code,
+			PlayerCount: len(room.clients),
+			Spectators:  len(room.spectators),
+			Phase:       string(room.phase),
+			Settings:    room.settings,
+			Password:    room.password != nil,
+		}
+		if host, exists := room.players[room.host]; exists {
+			summary.Host = host.name
+		}
+		rooms = append(rooms, summary)
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		wi, wj := rooms[i].Phase == protocol.PhaseWaiting, rooms[j].Phase == protocol.PhaseWaiting
+		if wi != wj {
+			return wi
+		}
+		if rooms[i].PlayerCount != rooms[j].PlayerCount {
+			return rooms[i].PlayerCount > rooms[j].PlayerCount
+		}
+		return rooms[i].Code < rooms[j].Code
+	})
+	return rooms
+}
+
+// HandleListRooms serves the public rooms as JSON
+func HandleListRooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(protocol.RoomListResponse{Rooms: roomManager.PublicRooms()}); err != nil {
+		log.Printf("Failed to write room list: %v", err)
+	}
+}
+
+func handleListRooms(client *Client, clientID string) {
+	rooms := roomManager.PublicRooms()
+	client.Send(protocol.RoomListResponse{Rooms: rooms})
+	log.Printf("📋 Sent %d public rooms to client %s", len(rooms), clientID)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// ChatScrollback is how many recent chat messages a room keeps
+// for players who join later
+const ChatScrollback = 50
+
+// ChatInterval is the minimum time between chat messages from
+// a single player
+var ChatInterval = 500 * time.Millisecond
+
+var ErrChatTooFast = errors.New("you are sending messages too quickly")
+
+// RecordChat stamps a chat message from a client and adds it to its
+// room's scrollback, returning the room code to relay it to
+func (rm *RoomManager) RecordChat(clientID, text string) (string, protocol.ChatMessage, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return "", protocol.ChatMessage{}, err
+	}
+	sender := room.players[clientID]
+	now := time.Now()
+	if now.Sub(sender.lastChat) < ChatInterval {
+		return "", protocol.ChatMessage{}, ErrChatTooFast
+	}
+	sender.lastChat = now
+
+	msg := protocol.ChatMessage{
+		PlayerIndex: sender.index,
+		Name:        sender.name,
+		Color:       sender.color,
+		Text:        cleanText(text),
+		SentAt:      now.UnixMilli(),
+	}
+	room.chat = append(room.chat, msg)
+	if len(room.chat) > ChatScrollback {
+		room.chat = room.chat[len(room.chat)-ChatScrollback:]
+	}
+	return roomCode, msg, nil
+}
+
+// ChatHistory returns a copy of a room's scrollback, oldest first
+func (rm *RoomManager) ChatHistory(roomCode string) []protocol.ChatMessage {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	room, exists := rm.rooms[roomCode]
+	if !exists {
+		return nil
+	}
+	return append([]protocol.ChatMessage(nil), room.chat...)
+}
+
+func handleChat(client *Client, clientID, text string) {
+	roomCode, msg, err := roomManager.RecordChat(clientID, text)
+	if err != nil {
+		log.Printf("Rejected chat from client %s: %v", clientID, err)
+		client.SendError(err)
+		return
+	}
+
+	// Senders get their own message back, stamped like everyone else's
+	roomManager.BroadcastToRoom(roomCode, "", protocol.ChatResponse{Code: roomCode, Message: msg})
+	log.Printf("💬 Relayed chat from client %s to room %s", clientID, roomCode)
+}
+
+// sendChatHistory catches a newly arrived player up on the room's chat
+func sendChatHistory(client *Client, roomCode string) {
+	if history := roomManager.ChatHistory(roomCode); len(history) > 0 {
+		client.Send(protocol.ChatHistoryResponse{Code: roomCode, Messages: history})
+	}
+}
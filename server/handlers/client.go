@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// SendQueueSize bounds how many outbound messages may be queued for a
+// client. A client that falls this far behind is evicted.
+var SendQueueSize = 64
+
+var (
+	// PingPeriod is how often the server pings each client.
+	// It must be shorter than PongWait.
+	PingPeriod = 25 * time.Second
+	// PongWait is how long a client may go without answering a ping
+	// or sending anything before it is treated as dead
+	PongWait = 60 * time.Second
+	// WriteWait is how long a single write may block before the
+	// connection is treated as dead
+	WriteWait = 10 * time.Second
+)
+
+// Client is a single WebSocket connection. All writes to the
+// connection happen on the client's own writer goroutine, fed
+// through a bounded queue, since gorilla/websocket does not
+// allow concurrent writers.
+type Client struct {
+	conn         *websocket.Conn
+	send         chan frame
+	done         chan struct{}
+	closeOnce    sync.Once
+	pingPeriod   time.Duration
+	pongWait     time.Duration
+	writeWait    time.Duration
+	capabilities map[string]bool // settled during the handshake
+	heartbeat    bool            // whether the server pings the client
+	playerID     string          // identifies the player across connections, if the client sent one
+}
+
+// frame is a single queued websocket message
+type frame struct {
+	messageType int
+	data        []byte
+}
+
+// newClient wraps conn and arms its read deadline. Heartbeat
+// timings are fixed when the client is created. Nothing is written
+// to the client until start is called.
+func newClient(conn *websocket.Conn) *Client {
+	c := &Client{
+		conn:       conn,
+		send:       make(chan frame, SendQueueSize),
+		done:       make(chan struct{}),
+		pingPeriod: PingPeriod,
+		pongWait:   PongWait,
+		writeWait:  WriteWait,
+	}
+	c.extendReadDeadline()
+	conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+	return c
+}
+
+// start starts the client's writer goroutine once the handshake has
+// settled its capabilities. Clients that did not enable the heartbeat
+// are not pinged, but are still dropped if they go quiet for PongWait.
+func (c *Client) start() {
+	c.heartbeat = c.Supports(protocol.CapabilityHeartbeat)
+	go c.writePump()
+}
+
+// extendReadDeadline gives the client another PongWait to be heard
+// from. A read that hits the deadline ends the client's read loop.
+func (c *Client) extendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+}
+
+// writePump delivers queued messages, and pings the client if it
+// enabled the heartbeat, until the client is closed
+func (c *Client) writePump() {
+	var ping <-chan time.Time
+	if c.heartbeat {
+		ticker := time.NewTicker(c.pingPeriod)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case f := <-c.send:
+			if err := c.write(f.messageType, f.data); err != nil {
+				log.Printf("Error sending message: %v", err)
+				c.Close()
+				return
+			}
+			if f.messageType == websocket.CloseMessage {
+				c.Close()
+				return
+			}
+		case <-ping:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				log.Printf("💔 Heartbeat failed, dropping client: %v", err)
+				c.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// write sends one frame, giving up after WriteWait
+func (c *Client) write(messageType int, data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// Send queues a message for delivery. It is safe to call from any
+// goroutine, never blocks, and does nothing for a nil client.
+func (c *Client) Send(msg protocol.Message) {
+	if c == nil {
+		// Client is disconnected and waiting to resume
+		return
+	}
+	data, err := protocol.Encode(msg)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+	c.sendRaw(data)
+}
+
+// sendRaw queues an already encoded message, evicting
+// the client if its queue is full
+func (c *Client) sendRaw(data []byte) {
+	if c == nil {
+		return
+	}
+	c.enqueue(frame{messageType: websocket.TextMessage, data: data})
+}
+
+func (c *Client) enqueue(f frame) {
+	select {
+	case <-c.done:
+	case c.send <- f:
+	default:
+		log.Printf("⚠️ Evicting slow client: outbound queue full")
+		c.Close()
+	}
+}
+
+// SendError reports a rejected request to the client, tagging
+// errors the client can act on with a machine-readable reason
+func (c *Client) SendError(err error) {
+	c.Send(protocol.ErrorResponse{
+		Message: err.Error(),
+		Reason:  reasonFor(err),
+	})
+}
+
+// Supports reports whether a capability was enabled for the
+// client during the handshake
+func (c *Client) Supports(capability string) bool {
+	return c != nil && c.capabilities[capability]
+}
+
+// CloseAfterQueued closes the connection with a close frame once
+// everything already queued has been written
+func (c *Client) CloseAfterQueued(code int, text string) {
+	c.enqueue(frame{messageType: websocket.CloseMessage, data: websocket.FormatCloseMessage(code, text)})
+}
+
+// Close stops the writer and closes the connection, which in turn
+// ends the client's read loop. It is safe to call more than once.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// broadcast sends msg to every connected client in the room except
+// the one with ID except, then to its spectators. The caller must
+// hold the room manager's lock.
+func (room *Room) broadcast(msg protocol.Message, except string) {
+	data, err := protocol.Encode(msg)
+	if err != nil {
+		log.Printf("Error marshaling broadcast message: %v", err)
+		return
+	}
+	for clientID, client := range room.clients {
+		if clientID != except {
+			client.sendRaw(data)
+		}
+	}
+	for _, client := range room.spectators {
+		client.sendRaw(data)
+	}
+}
+
+// broadcastSpectators sends msg to the room's spectators only.
+// The caller must hold the room manager's lock.
+func (room *Room) broadcastSpectators(msg protocol.Message) {
+	for _, client := range room.spectators {
+		client.Send(msg)
+	}
+}
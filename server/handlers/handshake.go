@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"runtime/debug"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// Capabilities lists the optional features this server supports.
+// Only those the client also advertises are enabled for it.
+var Capabilities = []string{
+	protocol.CapabilityHeartbeat,
+	protocol.CapabilityProgress,
+	protocol.CapabilityResume,
+}
+
+var ErrUpdateRequired = errors.New("this version of teletyperacer is out of date, please update to play online")
+
+// buildVersion reports the module version the server was built
+// from, or "dev" for local builds
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// awaitHello reads the client's opening message and answers it.
+// Clients that predate the handshake open with something other
+// than hello and are treated as out of date.
+func awaitHello(client *Client, clientID string) bool {
+	_, data, err := client.conn.ReadMessage()
+	if err != nil {
+		log.Printf("WebSocket read error for client %s during handshake: %v", clientID, err)
+		return false
+	}
+	client.extendReadDeadline()
+
+	// A malformed or missing hello decodes as version zero
+	msg, _ := protocol.Decode(data)
+	req, _ := msg.(protocol.HelloRequest)
+	return handleHello(client, clientID, req)
+}
+
+// handleHello checks the client's protocol version and settles
+// which capabilities are enabled for the connection. Clients newer
+// than the server are asked to speak the server's version.
+func handleHello(client *Client, clientID string, req protocol.HelloRequest) bool {
+	if req.ProtocolVersion < protocol.MinVersion {
+		log.Printf("🚫 Rejected client %s: protocol v%d (version %q), need v%d or newer",
+			clientID, req.ProtocolVersion, req.Version, protocol.MinVersion)
+		client.SendError(ErrUpdateRequired)
+		return false
+	}
+
+	offered := make(map[string]bool, len(req.Capabilities))
+	for _, capability := range req.Capabilities {
+		offered[capability] = true
+	}
+	if len(req.PlayerID) <= maxPlayerIDLength {
+		client.playerID = req.PlayerID
+	}
+	client.capabilities = make(map[string]bool)
+	enabled := []string{}
+	for _, capability := range Capabilities {
+		if offered[capability] {
+			client.capabilities[capability] = true
+			enabled = append(enabled, capability)
+		}
+	}
+
+	version := min(req.ProtocolVersion, protocol.Version)
+	client.Send(protocol.WelcomeResponse{
+		ProtocolVersion:    version,
+		MinProtocolVersion: protocol.MinVersion,
+		Version:            buildVersion(),
+		Capabilities:       enabled,
+		Languages:          passageLibrary.Languages(),
+	})
+	log.Printf("🤝 Client %s speaks protocol v%d (offered v%d, version %q) with %v", clientID, version, req.ProtocolVersion, req.Version, enabled)
+	return true
+}
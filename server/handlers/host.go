@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+var (
+	ErrPlayerNotFound = errors.New("no such player in this room")
+	ErrKickSelf       = errors.New("you cannot kick yourself")
+)
+
+// hostRoomOf looks up the room a client belongs to, failing unless
+// the client is its host. The caller must hold rm.mu.
+func (rm *RoomManager) hostRoomOf(clientID string) (string, *Room, error) {
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return "", nil, err
+	}
+	if room.host != clientID {
+		return "", nil, ErrNotHost
+	}
+	return roomCode, room, nil
+}
+
+// playerAt returns the client ID of the player with the given
+// roster index. The caller must hold rm.mu.
+func (room *Room) playerAt(index int) (string, bool) {
+	for clientID, p := range room.players {
+		if p.index == index {
+			return clientID, true
+		}
+	}
+	return "", false
+}
+
+// migrateHostLocked hands the host role to the longest-present player,
+// preferring players who are still connected. It does nothing if the
+// room is empty. The caller must hold rm.mu.
+func (rm *RoomManager) migrateHostLocked(roomCode string, room *Room) {
+	next, nextIndex, nextConnected := "", 0, false
+	for clientID, p := range room.players {
+		if clientID == room.host {
+			continue
+		}
+		connected := room.clients[clientID] != nil
+		better := next == "" ||
+			(connected && !nextConnected) ||
+			(connected == nextConnected && p.index < nextIndex)
+		if better {
+			next, nextIndex, nextConnected = clientID, p.index, connected
+		}
+	}
+	if next == "" {
+		return
+	}
+	room.host = next
+	log.Printf("👑 Host of room %s passed to client %s", roomCode, next)
+}
+
+// KickPlayer removes the player with the given index from the
+// host's room, telling them why
+func (rm *RoomManager) KickPlayer(clientID string, index int) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+	target, exists := room.playerAt(index)
+	if !exists {
+		return ErrPlayerNotFound
+	}
+	if target == clientID {
+		return ErrKickSelf
+	}
+
+	room.clients[target].Send(protocol.RoomClosedResponse{Code: roomCode, Reason: protocol.ClosedKicked})
+	rm.removeClientLocked(roomCode, target)
+	return nil
+}
+
+// SetLocked opens or closes the host's room to new players
+func (rm *RoomManager) SetLocked(clientID string, locked bool) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+	room.locked = locked
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+// TransferHost makes the player with the given index the new host
+func (rm *RoomManager) TransferHost(clientID string, index int) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+	target, exists := room.playerAt(index)
+	if !exists {
+		return ErrPlayerNotFound
+	}
+	room.host = target
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+func handleKickPlayer(client *Client, clientID string, index int) {
+	log.Printf("🥾 Client %s kicking player %d", clientID, index)
+
+	if err := roomManager.KickPlayer(clientID, index); err != nil {
+		log.Printf("Rejected kickPlayer from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
+
+func handleLockRoom(client *Client, clientID string, locked bool) {
+	log.Printf("🔒 Client %s setting room lock to %t", clientID, locked)
+
+	if err := roomManager.SetLocked(clientID, locked); err != nil {
+		log.Printf("Rejected lockRoom from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
+
+func handleTransferHost(client *Client, clientID string, index int) {
+	log.Printf("👑 Client %s transferring host to player %d", clientID, index)
+
+	if err := roomManager.TransferHost(clientID, index); err != nil {
+		log.Printf("Rejected transferHost from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/givensuman/teletyperacer/server/passages"
+)
+
+// passageLibrary holds the texts races are run on,
+// starting with the passages embedded in the binary
+var passageLibrary = func() *passages.Library {
+	lib, err := passages.NewLibrary()
+	if err != nil {
+		log.Fatalf("Failed to load embedded passages: %v", err)
+	}
+	return lib
+}()
+
+// LoadPassages extends the passage library with the files in dir
+func LoadPassages(dir string) error {
+	if err := passageLibrary.LoadDir(dir); err != nil {
+		return err
+	}
+	log.Printf("📚 Loaded passages from %s, %d available", dir, passageLibrary.Len())
+	return nil
+}
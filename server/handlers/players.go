@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// player is a member of a room's roster
+type player struct {
+	index int    // stable for as long as the player stays in the room
+	name  string // nickname shown to other players
+	color int    // palette slot, unique within the room
+	ready bool   // whether the player is ready to race
+
+	// unready is set while the player has un-readied since the last
+	// race, which holds back the room's automatic start
+	unready bool
+
+	rematch bool // voted to race again after the last race
+	points  int  // earned over every race played in the room
+
+	ratingID     string // whose rating the player's races count towards
+	rating       int
+	ratingChange int // from the player's last race in the room
+
+	lastChat time.Time // when the player last sent a chat message
+}
+
+// cleanText strips unprintable characters from text
+// and collapses its whitespace
+func cleanText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// sanitizeName cleans up a requested nickname, falling back to a
+// numbered default when nothing usable is left
+func sanitizeName(name string, index int) string {
+	name = cleanText(name)
+	if runes := []rune(name); len(runes) > protocol.MaxNameLength {
+		name = string(runes[:protocol.MaxNameLength])
+	}
+	if name == "" {
+		return fmt.Sprintf("Player %d", index+1)
+	}
+	return name
+}
+
+// freeColor returns the lowest palette slot no current player is
+// using. The caller must hold rm.mu.
+func (room *Room) freeColor() int {
+	taken := make(map[int]bool, len(room.players))
+	for _, p := range room.players {
+		taken[p.color] = true
+	}
+	color := 0
+	for taken[color] {
+		color++
+	}
+	return color
+}
+
+// roster lists the room's players in the order they joined.
+// The caller must hold rm.mu.
+func (room *Room) roster() []protocol.Player {
+	roster := make([]protocol.Player, 0, len(room.players))
+	for clientID, p := range room.players {
+		roster = append(roster, protocol.Player{
+			Index:   p.index,
+			Name:    p.name,
+			Color:   p.color,
+			Host:    clientID == room.host,
+			Ready:   p.ready,
+			Rematch: p.rematch,
+			Points:  p.points,
+
+			Rating:       p.rating,
+			RatingChange: p.ratingChange,
+		})
+	}
+	sort.Slice(roster, func(i, j int) bool { return roster[i].Index < roster[j].Index })
+	return roster
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// ProgressInterval is the minimum time between relayed progress
+// updates from a single client. Updates arriving faster are dropped.
+var ProgressInterval = 100 * time.Millisecond
+
+// MaxTypingSpeed is the fastest anyone is believed to type, in
+// characters per second. Progress further into the passage than this
+// allows since the race started, plus a second's grace, is rejected.
+var MaxTypingSpeed = 40.0
+
+var ErrTooFast = errors.New("progress is faster than anyone can type")
+
+var (
+	errProgressThrottled = errors.New("progress update throttled")
+	errPassageComplete   = errors.New("passage already completed")
+)
+
+// playerProgress is the latest typing progress reported by a client
+type playerProgress struct {
+	position  int
+	wpm       float64
+	errors    int
+	updatedAt time.Time
+}
+
+// RecordProgress stores a client's latest progress and returns the
+// room code to relay it to, along with the update to relay. Positions
+// past the end of the passage are clamped to it. It returns
+// ErrTooFast for positions no one could have typed in the time
+// elapsed, errProgressThrottled when the client is reporting too
+// often, and errPassageComplete once the client has reached the end.
+func (rm *RoomManager) RecordProgress(clientID string, req protocol.ProgressRequest) (string, protocol.PlayerProgressResponse, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return "", protocol.PlayerProgressResponse{}, err
+	}
+	if room.phase != PhaseRacing {
+		return "", protocol.PlayerProgressResponse{}, ErrNotRacing
+	}
+
+	if room.completed(clientID) {
+		return "", protocol.PlayerProgressResponse{}, errPassageComplete
+	}
+
+	textLen := len([]rune(room.passage.Text))
+	position := min(req.Position, textLen)
+	now := time.Now()
+	elapsed := now.Sub(room.startedAt) + time.Second
+	if float64(position) > elapsed.Seconds()*MaxTypingSpeed {
+		return "", protocol.PlayerProgressResponse{}, ErrTooFast
+	}
+	last, exists := room.progress[clientID]
+	// The update that completes the passage is never dropped, since
+	// its error count feeds into the final results
+	if exists && position < textLen && now.Sub(last.updatedAt) < ProgressInterval {
+		return "", protocol.PlayerProgressResponse{}, errProgressThrottled
+	}
+
+	room.progress[clientID] = &playerProgress{
+		position:  position,
+		wpm:       req.WPM,
+		errors:    req.Errors,
+		updatedAt: now,
+	}
+	return roomCode, protocol.PlayerProgressResponse{
+		PlayerIndex: room.players[clientID].index,
+		Position:    position,
+		WPM:         req.WPM,
+		Errors:      req.Errors,
+	}, nil
+}
+
+// completed reports whether a client's recorded progress reaches the
+// end of the passage. The caller must hold rm.mu.
+func (room *Room) completed(clientID string) bool {
+	p, exists := room.progress[clientID]
+	return exists && p.position >= len([]rune(room.passage.Text))
+}
+
+// relayProgress sends a player's progress to everyone else watching
+// the room who enabled progress updates
+func (rm *RoomManager) relayProgress(roomCode, senderID string, msg protocol.PlayerProgressResponse) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	room, exists := rm.rooms[roomCode]
+	if !exists {
+		return
+	}
+	data, err := protocol.Encode(msg)
+	if err != nil {
+		log.Printf("Error marshaling progress: %v", err)
+		return
+	}
+	for clientID, client := range room.clients {
+		if clientID != senderID && client.Supports(protocol.CapabilityProgress) {
+			client.sendRaw(data)
+		}
+	}
+	for _, client := range room.spectators {
+		if client.Supports(protocol.CapabilityProgress) {
+			client.sendRaw(data)
+		}
+	}
+}
+
+func handleProgress(client *Client, clientID string, req protocol.ProgressRequest) {
+	roomCode, progress, err := roomManager.RecordProgress(clientID, req)
+	if errors.Is(err, errProgressThrottled) || errors.Is(err, errPassageComplete) {
+		return
+	}
+	if err != nil {
+		log.Printf("Rejected progress from client %s: %v", clientID, err)
+		client.SendError(err)
+		return
+	}
+
+	roomManager.relayProgress(roomCode, clientID, progress)
+}
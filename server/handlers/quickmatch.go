@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// QuickMatchPlayers is how many queued clients are matched into a
+// room straight away, and how many a quick-match room needs before
+// it starts on its own. A room made once QuickMatchWait runs out
+// starts with however many were waiting.
+const QuickMatchPlayers = 2
+
+// QuickMatchDelay is the countdown, in seconds, before a quick-match
+// room races once it has enough players. Anyone who quick-matches in
+// the meantime joins it.
+const QuickMatchDelay = 10
+
+// QuickMatchWait is the longest a client waits in the queue before
+// a room is made for whoever is there
+var QuickMatchWait = 15 * time.Second
+
+// RankedSpread is the widest rating gap ranked players are matched
+// across straight away. It grows by RankedWiden for every second the
+// longest waiting of them has been queued.
+const (
+	RankedSpread = 100
+	RankedWiden  = 10
+)
+
+// RankedInterval is how often the ranked queue is matched again as
+// the spreads widen
+var RankedInterval = time.Second
+
+// queued is a client waiting for a quick match
+type queued struct {
+	clientID string
+	client   *Client
+	name     string
+	rating   int       // ranked queue only
+	since    time.Time // when the client joined the queue
+}
+
+// QuickMatch puts a client into an open quick-match room if there is
+// one, and otherwise queues it until enough clients are waiting.
+// Ranked clients always queue, and are only matched with each other.
+func (rm *RoomManager) QuickMatch(clientID string, client *Client, name string, ranked bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.leaveLocked(clientID)
+	if ranked {
+		ratingID := client.playerID
+		if ratingID == "" {
+			ratingID = clientID
+		}
+		rm.rankedQueue = append(rm.rankedQueue, queued{
+			clientID: clientID,
+			client:   client,
+			name:     name,
+			rating:   ratingStore.Get(ratingID),
+			since:    time.Now(),
+		})
+		rm.matchRankedLocked(time.Now())
+		rm.sendRankedStatusLocked()
+		return
+	}
+
+	if code, room := rm.openQuickRoomLocked(); room != nil {
+		rm.addClientLocked(code, clientID, client, name)
+		client.Send(protocol.RoomJoinedResponse{Code: code})
+		if len(room.chat) > 0 {
+			client.Send(protocol.ChatHistoryResponse{Code: code, Messages: room.chat})
+		}
+		rm.broadcastRoomStateLocked(code, room)
+		return
+	}
+
+	rm.queue = append(rm.queue, queued{clientID: clientID, client: client, name: name, since: time.Now()})
+	if len(rm.queue) >= QuickMatchPlayers {
+		rm.matchLocked()
+		return
+	}
+	if rm.queueDeadline.IsZero() {
+		at := time.Now().Add(QuickMatchWait)
+		rm.queueDeadline = at
+		rm.queueTimer = time.AfterFunc(QuickMatchWait, func() {
+			rm.matchQueued(at)
+		})
+	}
+	rm.sendQueueStatusLocked()
+}
+
+// openQuickRoomLocked returns the fullest quick-match room still
+// taking players, if any. The caller must hold rm.mu.
+func (rm *RoomManager) openQuickRoomLocked() (string, *Room) {
+	var best *Room
+	bestCode := ""
+	for code, room := range rm.rooms {
+		open := room.quick && room.settings.Public && !room.locked &&
+			room.phase == PhaseWaiting && len(room.clients) < room.settings.MaxPlayers
+		if open && (best == nil || len(room.clients) > len(best.clients)) {
+			best, bestCode = room, code
+		}
+	}
+	return bestCode, best
+}
+
+// matchLocked makes a public room for everyone in the queue and
+// starts its countdown. The caller must hold rm.mu.
+func (rm *RoomManager) matchLocked() {
+	matched := rm.queue
+	rm.queue = nil
+	rm.stopQueueTimerLocked()
+	if len(matched) == 0 {
+		return
+	}
+
+	code, room := rm.matchRoomLocked(matched)
+	room.quick = true
+	room.settings.Public = true
+	log.Printf("⚡ Quick match put %d players in room %s", len(matched), code)
+	rm.broadcastRoomStateLocked(code, room)
+}
+
+// matchRoomLocked makes a room for matched clients that starts on its
+// own once it has QuickMatchPlayers, or all of them if fewer were
+// matched, hosted by whoever waited longest.
+// The caller must hold rm.mu and broadcast the room's state once it is
+// set up.
+func (rm *RoomManager) matchRoomLocked(matched []queued) (string, *Room) {
+	code := rm.newRoomLocked(matched[0].clientID)
+	room := rm.rooms[code]
+	room.autoStart = protocol.AutoStart{MinPlayers: min(len(matched), QuickMatchPlayers), Delay: QuickMatchDelay}
+	for _, q := range matched {
+		rm.addClientLocked(code, q.clientID, q.client, q.name)
+		q.client.Send(protocol.RoomJoinedResponse{Code: code})
+	}
+	return code, room
+}
+
+// matchRankedLocked groups ranked clients whose ratings are within
+// the spread of the longest waiting client still unmatched, each
+// group into its own locked room. Anyone left over is matched again
+// after RankedInterval. The caller must hold rm.mu.
+func (rm *RoomManager) matchRankedLocked(now time.Time) {
+	var waiting []queued
+	matched := make(map[string]bool)
+	for i, anchor := range rm.rankedQueue {
+		if matched[anchor.clientID] {
+			continue
+		}
+		spread := RankedSpread + RankedWiden*int(now.Sub(anchor.since)/time.Second)
+		group := []queued{anchor}
+		for _, q := range rm.rankedQueue[i+1:] {
+			if !matched[q.clientID] && abs(q.rating-anchor.rating) <= spread && len(group) < MaxPlayers {
+				group = append(group, q)
+			}
+		}
+		if len(group) < 2 {
+			waiting = append(waiting, anchor)
+			continue
+		}
+		for _, q := range group {
+			matched[q.clientID] = true
+		}
+		code, room := rm.matchRoomLocked(group)
+		room.locked = true
+		room.ranked = true
+		log.Printf("🏅 Ranked match put %d players in room %s", len(group), code)
+		rm.broadcastRoomStateLocked(code, room)
+	}
+	rm.rankedQueue = waiting
+
+	if len(waiting) > 0 && rm.rankedTimer == nil {
+		rm.rankedTimer = time.AfterFunc(RankedInterval, rm.rematchRanked)
+	}
+}
+
+// rematchRanked matches the ranked queue again with wider spreads
+func (rm *RoomManager) rematchRanked() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.rankedTimer = nil
+	waiting := len(rm.rankedQueue)
+	rm.matchRankedLocked(time.Now())
+	if len(rm.rankedQueue) != waiting {
+		rm.sendRankedStatusLocked()
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// matchQueued matches whoever is queued once the wait scheduled
+// for at runs out, unless the queue has since been matched or emptied
+func (rm *RoomManager) matchQueued(at time.Time) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if !rm.queueDeadline.Equal(at) {
+		return
+	}
+	rm.queueTimer = nil
+	rm.matchLocked()
+}
+
+// queuedClient returns the connection a client is queued with, or
+// nil if it is not queued. The caller must hold rm.mu.
+func (rm *RoomManager) queuedClient(clientID string) *Client {
+	for _, q := range slices.Concat(rm.queue, rm.rankedQueue) {
+		if q.clientID == clientID {
+			return q.client
+		}
+	}
+	return nil
+}
+
+// dequeueLocked takes a client out of either quick-match queue, if
+// it is in one. The caller must hold rm.mu.
+func (rm *RoomManager) dequeueLocked(clientID string) {
+	isClient := func(q queued) bool { return q.clientID == clientID }
+	if i := slices.IndexFunc(rm.rankedQueue, isClient); i >= 0 {
+		rm.rankedQueue = slices.Delete(rm.rankedQueue, i, i+1)
+		log.Printf("🏅 Client %s left the ranked queue", clientID)
+		rm.sendRankedStatusLocked()
+		return
+	}
+	i := slices.IndexFunc(rm.queue, isClient)
+	if i < 0 {
+		return
+	}
+	rm.queue = slices.Delete(rm.queue, i, i+1)
+	log.Printf("⚡ Client %s left the quick-match queue", clientID)
+	if len(rm.queue) == 0 {
+		rm.stopQueueTimerLocked()
+	}
+	rm.sendQueueStatusLocked()
+}
+
+// stopQueueTimerLocked cancels the pending queue deadline.
+// The caller must hold rm.mu.
+func (rm *RoomManager) stopQueueTimerLocked() {
+	if rm.queueTimer != nil {
+		rm.queueTimer.Stop()
+		rm.queueTimer = nil
+	}
+	rm.queueDeadline = time.Time{}
+}
+
+// sendQueueStatusLocked tells every client in the quick-match queue
+// how the search is going. The caller must hold rm.mu.
+func (rm *RoomManager) sendQueueStatusLocked() {
+	left := time.Until(rm.queueDeadline)
+	status := protocol.QueueStatusResponse{
+		Waiting: len(rm.queue),
+		MatchIn: max(int((left+time.Second-1)/time.Second), 0),
+	}
+	for _, q := range rm.queue {
+		q.client.Send(status)
+	}
+}
+
+// sendRankedStatusLocked tells every client in the ranked queue how
+// many are searching. Ranked matches have no deadline. The caller
+// must hold rm.mu.
+func (rm *RoomManager) sendRankedStatusLocked() {
+	for _, q := range rm.rankedQueue {
+		q.client.Send(protocol.QueueStatusResponse{Waiting: len(rm.rankedQueue)})
+	}
+}
+
+func handleQuickMatch(client *Client, clientID string, req protocol.QuickMatchRequest) {
+	log.Printf("⚡ Client %s looking for a quick match (ranked: %t)", clientID, req.Ranked)
+
+	roomManager.QuickMatch(clientID, client, req.Name, req.Ranked)
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/givensuman/teletyperacer/server/passages"
+)
+
+// RacePhase describes where a room is in the race lifecycle
+type RacePhase string
+
+const (
+	PhaseWaiting   RacePhase = protocol.PhaseWaiting
+	PhaseCountdown RacePhase = protocol.PhaseCountdown
+	PhaseRacing    RacePhase = protocol.PhaseRacing
+	PhaseFinished  RacePhase = protocol.PhaseFinished
+)
+
+var (
+	// CountdownDuration is how long a room counts down before racing
+	CountdownDuration = 3 * time.Second
+	// RaceTimeout ends a race that not every player has finished
+	RaceTimeout = 5 * time.Minute
+)
+
+var (
+	ErrNotInRoom    = errors.New("you are not in a room")
+	ErrNotHost      = errors.New("only the host can do that")
+	ErrRaceStarted  = errors.New("the race has already started")
+	ErrNotRacing    = errors.New("there is no race in progress")
+	ErrAlreadyDone  = errors.New("you have already finished this race")
+	ErrNotComplete  = errors.New("you have not typed the whole passage")
+	ErrRoomNotFound = errors.New("room not found")
+)
+
+// StartRace moves the client's room into the countdown phase.
+// Only the host may start a race, and only from the waiting
+// or finished phases.
+func (rm *RoomManager) StartRace(clientID string, filter passages.Filter) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+	if room.phase != PhaseWaiting && room.phase != PhaseFinished {
+		return ErrRaceStarted
+	}
+	return rm.startRaceLocked(roomCode, room, filter)
+}
+
+// startRaceLocked picks a passage and begins the countdown.
+// The caller must hold rm.mu.
+func (rm *RoomManager) startRaceLocked(roomCode string, room *Room, filter passages.Filter) error {
+	// Races in the same room get a fresh passage each time
+	filter.AvoidID = room.passage.ID
+	passage, err := passageLibrary.Pick(room.filter(filter))
+	if err != nil {
+		return err
+	}
+
+	room.passage = passage.Format(room.settings.Punctuation, room.settings.Numbers)
+	room.round++
+	room.finishers = nil
+	room.progress = make(map[string]*playerProgress)
+	room.autoStartAt = time.Time{}
+	room.entrants = room.entrants[:0]
+	for _, p := range room.players {
+		room.entrants = append(room.entrants, p.ratingID)
+		// Everyone readies up again for the next race
+		p.ready = false
+		p.unready = false
+		p.rematch = false
+	}
+	rm.setPhase(roomCode, room, PhaseCountdown)
+
+	round := room.round
+	room.stopTimer()
+	room.timer = time.AfterFunc(room.countdown(), func() {
+		rm.advance(roomCode, round, PhaseCountdown, PhaseRacing)
+	})
+	return nil
+}
+
+// FinishRace records that a client has completed the passage,
+// ending the race once every player in the room is done. The
+// client's reported progress must already reach the end.
+func (rm *RoomManager) FinishRace(clientID string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return err
+	}
+	if room.phase != PhaseRacing {
+		return ErrNotRacing
+	}
+	if room.hasFinished(clientID) {
+		return ErrAlreadyDone
+	}
+	// Only progress the server has seen counts towards a finish
+	if !room.completed(clientID) {
+		return ErrNotComplete
+	}
+
+	// Placement is decided by the order finishes reach the server
+	room.finishers = append(room.finishers, finish{clientID: clientID, at: time.Now()})
+	finished := protocol.PlayerFinishedResponse{
+		PlayerIndex: room.players[clientID].index,
+		Place:       len(room.finishers),
+	}
+	room.broadcast(finished, "")
+
+	if room.allFinished() {
+		rm.endRace(roomCode, room)
+	}
+	return nil
+}
+
+// GetRoomPhase returns the current phase of a room
+func (rm *RoomManager) GetRoomPhase(roomCode string) RacePhase {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	if room, exists := rm.rooms[roomCode]; exists {
+		return room.phase
+	}
+	return PhaseWaiting
+}
+
+// advance performs a timed transition, provided the room still
+// exists and has not moved on since the timer was scheduled
+func (rm *RoomManager) advance(roomCode string, round int, from, to RacePhase) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	room, exists := rm.rooms[roomCode]
+	if !exists || room.round != round || room.phase != from {
+		return
+	}
+
+	if to == PhaseFinished {
+		rm.endRace(roomCode, room)
+		return
+	}
+
+	rm.setPhase(roomCode, room, to)
+	if to == PhaseRacing {
+		room.startedAt = time.Now()
+		room.timer = time.AfterFunc(RaceTimeout, func() {
+			rm.advance(roomCode, round, PhaseRacing, PhaseFinished)
+		})
+	}
+}
+
+// roomOf looks up the room a client belongs to. The caller must hold rm.mu.
+func (rm *RoomManager) roomOf(clientID string) (string, *Room, error) {
+	roomCode, exists := rm.clientToRoom[clientID]
+	if !exists {
+		return "", nil, ErrNotInRoom
+	}
+	room, exists := rm.rooms[roomCode]
+	if !exists {
+		return "", nil, ErrRoomNotFound
+	}
+	return roomCode, room, nil
+}
+
+// setPhase updates the room's phase and broadcasts the transition.
+// The caller must hold rm.mu.
+func (rm *RoomManager) setPhase(roomCode string, room *Room, phase RacePhase) {
+	room.phase = phase
+
+	resp := protocol.RacePhaseResponse{Code: roomCode, Phase: string(phase)}
+	if phase == PhaseCountdown {
+		resp.Countdown = int(room.countdown() / time.Second)
+		resp.Passage = &protocol.PassageResponse{
+			ID:     room.passage.ID,
+			Text:   room.passage.Text,
+			Source: room.passage.Source,
+			Author: room.passage.Author,
+		}
+	}
+	room.broadcast(resp, "")
+	log.Printf("🏁 Room %s entered phase %s", roomCode, phase)
+}
+
+// allFinished reports whether every player still in the room has finished
+func (room *Room) allFinished() bool {
+	for clientID := range room.clients {
+		if !room.hasFinished(clientID) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasFinished reports whether a client has finished the current race
+func (room *Room) hasFinished(clientID string) bool {
+	for _, f := range room.finishers {
+		if f.clientID == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// stopTimer cancels any pending phase transition
+func (room *Room) stopTimer() {
+	if room.timer != nil {
+		room.timer.Stop()
+		room.timer = nil
+	}
+}
+
+func handleStartRace(client *Client, clientID string, req protocol.StartRaceRequest) {
+	log.Printf("🚦 Client %s requesting race start", clientID)
+
+	filter := passages.Filter{
+		Length:     passages.Length(req.Length),
+		Difficulty: passages.Difficulty(req.Difficulty),
+	}
+	if err := roomManager.StartRace(clientID, filter); err != nil {
+		log.Printf("Rejected startRace from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
+
+func handleFinishRace(client *Client, clientID string) {
+	log.Printf("🏆 Client %s reports race finished", clientID)
+
+	if err := roomManager.FinishRace(clientID); err != nil {
+		log.Printf("Rejected finishRace from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
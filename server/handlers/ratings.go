@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/givensuman/teletyperacer/server/ratings"
+)
+
+// maxPlayerIDLength bounds the player IDs clients may identify
+// themselves with. Longer IDs are ignored.
+const maxPlayerIDLength = 64
+
+// ratingStore holds players' skill ratings. They are kept in memory
+// unless LoadRatings gives them a file.
+var ratingStore = ratings.NewStore()
+
+// LoadRatings reads saved ratings from path and saves them back
+// there after every race, until CloseRatings
+func LoadRatings(path string) error {
+	store, err := ratings.Open(path)
+	if err != nil {
+		return err
+	}
+	ratingStore = store
+	log.Printf("📈 Loaded ratings from %s, %d players rated", path, store.Len())
+	return nil
+}
+
+// CloseRatings saves any ratings not yet written to the file given
+// to LoadRatings
+func CloseRatings() error {
+	return ratingStore.Close()
+}
+
+// rateRace updates the ratings of everyone in the results of a ranked
+// race and records the changes on them. Players who left after the
+// race started are rated as coming last. The caller must hold rm.mu.
+func (room *Room) rateRace(results []protocol.RaceResult) {
+	placings := make([]ratings.Placing, 0, len(room.entrants))
+	members := make([]*player, len(results))
+	rated := make(map[string]bool, len(room.entrants))
+	for i, r := range results {
+		if clientID, exists := room.playerAt(r.PlayerIndex); exists {
+			members[i] = room.players[clientID]
+			placings = append(placings, ratings.Placing{ID: members[i].ratingID, Rank: r.Rank})
+			rated[members[i].ratingID] = true
+		}
+	}
+	for _, ratingID := range room.entrants {
+		if !rated[ratingID] {
+			placings = append(placings, ratings.Placing{ID: ratingID, Rank: len(results) + 1})
+			rated[ratingID] = true
+		}
+	}
+
+	changes := ratingStore.Record(placings)
+	for i := range results {
+		if member := members[i]; member != nil {
+			member.rating = ratingStore.Get(member.ratingID)
+			member.ratingChange = changes[member.ratingID]
+			results[i].Rating = member.rating
+			results[i].RatingChange = member.ratingChange
+		}
+	}
+}
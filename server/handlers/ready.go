@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/givensuman/teletyperacer/server/passages"
+)
+
+// DefaultAutoStartDelay is the auto-start countdown, in seconds,
+// rooms are created with
+const DefaultAutoStartDelay = 10
+
+// SetReady marks the client as ready or not for the next race.
+// Un-readying cancels any pending automatic start.
+func (rm *RoomManager) SetReady(clientID string, ready bool) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return err
+	}
+	if room.phase != PhaseWaiting && room.phase != PhaseFinished {
+		return ErrRaceStarted
+	}
+
+	p := room.players[clientID]
+	p.ready = ready
+	p.unready = !ready
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+// SetAutoStart changes when the host's room starts on its own
+func (rm *RoomManager) SetAutoStart(clientID string, autoStart protocol.AutoStart) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+
+	// A new delay only applies to countdowns started from now on
+	room.autoStart = autoStart
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+// shouldAutoStart reports whether the room's auto-start options
+// are currently met. Rooms only start on their own from the lobby,
+// so a finished race waits for a rematch. The caller must hold rm.mu.
+func (room *Room) shouldAutoStart() bool {
+	if room.phase != PhaseWaiting {
+		return false
+	}
+	if room.autoStart.MinPlayers > 0 && len(room.players) >= room.autoStart.MinPlayers && !room.held() {
+		return true
+	}
+	if !room.autoStart.WhenAllReady || len(room.players) < 2 {
+		return false
+	}
+	for _, p := range room.players {
+		if !p.ready {
+			return false
+		}
+	}
+	return true
+}
+
+// held reports whether any player has un-readied since the last
+// race. The caller must hold rm.mu.
+func (room *Room) held() bool {
+	for _, p := range room.players {
+		if p.unready {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleAutoStartLocked starts or cancels the room's automatic
+// start to match its options. The caller must hold rm.mu.
+func (rm *RoomManager) scheduleAutoStartLocked(roomCode string, room *Room) {
+	if room.phase != PhaseWaiting {
+		return
+	}
+	pending := !room.autoStartAt.IsZero()
+	switch should := room.shouldAutoStart(); {
+	case should && !pending:
+		at := time.Now().Add(time.Duration(room.autoStart.Delay) * time.Second)
+		room.autoStartAt = at
+		room.stopTimer()
+		room.timer = time.AfterFunc(time.Until(at), func() {
+			rm.autoStartRace(roomCode, at)
+		})
+		log.Printf("⏲️ Room %s starting automatically in %ds", roomCode, room.autoStart.Delay)
+	case !should && pending:
+		room.autoStartAt = time.Time{}
+		room.stopTimer()
+		log.Printf("⏲️ Automatic start of room %s cancelled", roomCode)
+	}
+}
+
+// autoStartRace starts the race scheduled for at, provided it
+// has not been cancelled or rescheduled in the meantime
+func (rm *RoomManager) autoStartRace(roomCode string, at time.Time) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	room, exists := rm.rooms[roomCode]
+	if !exists || !room.autoStartAt.Equal(at) {
+		return
+	}
+	room.timer = nil
+	if err := rm.startRaceLocked(roomCode, room, passages.Filter{}); err != nil {
+		room.autoStartAt = time.Time{}
+		log.Printf("Automatic start of room %s failed: %v", roomCode, err)
+	}
+}
+
+// startingIn returns the whole seconds left before a pending
+// automatic start, or zero if none is pending. The caller must
+// hold rm.mu.
+func (room *Room) startingIn() int {
+	if room.autoStartAt.IsZero() {
+		return 0
+	}
+	left := time.Until(room.autoStartAt)
+	return max(int((left+time.Second-1)/time.Second), 1)
+}
+
+func handleSetReady(client *Client, clientID string, ready bool) {
+	log.Printf("🙋 Client %s setting ready to %t", clientID, ready)
+
+	if err := roomManager.SetReady(clientID, ready); err != nil {
+		log.Printf("Rejected setReady from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
+
+func handleSetAutoStart(client *Client, clientID string, autoStart protocol.AutoStart) {
+	log.Printf("⏲️ Client %s changing auto-start to %+v", clientID, autoStart)
+
+	if err := roomManager.SetAutoStart(clientID, autoStart); err != nil {
+		log.Printf("Rejected setAutoStart from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+var ErrNoRematch = errors.New("there is no finished race to rematch")
+
+// awardPoints credits each player with points for their placing,
+// one for last place up to one per player for the winner. Players
+// who did not finish score nothing. The caller must hold rm.mu.
+func (room *Room) awardPoints(results []protocol.RaceResult) {
+	for i := range results {
+		r := &results[i]
+		if r.Finished {
+			r.Points = len(results) - r.Rank + 1
+		}
+		if clientID, exists := room.playerAt(r.PlayerIndex); exists {
+			member := room.players[clientID]
+			member.points += r.Points
+			r.TotalPoints = member.points
+		}
+	}
+	room.rounds++
+}
+
+// VoteRematch records whether a client wants to race the
+// room's players again
+func (rm *RoomManager) VoteRematch(clientID string, vote bool) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return err
+	}
+	if room.phase != PhaseFinished {
+		return ErrNoRematch
+	}
+
+	room.players[clientID].rematch = vote
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+// AcceptRematch returns the host's room to the lobby with the same
+// code, roster and points, ready for a fresh passage
+func (rm *RoomManager) AcceptRematch(clientID string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+	if room.phase != PhaseFinished {
+		return ErrNoRematch
+	}
+
+	for _, p := range room.players {
+		p.rematch = false
+		p.ready = false
+		p.unready = false
+	}
+	room.finishers = nil
+	room.progress = make(map[string]*playerProgress)
+	rm.setPhase(roomCode, room, PhaseWaiting)
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+func handleVoteRematch(client *Client, clientID string, vote bool) {
+	log.Printf("🔁 Client %s voting %t for a rematch", clientID, vote)
+
+	if err := roomManager.VoteRematch(clientID, vote); err != nil {
+		log.Printf("Rejected voteRematch from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
+
+func handleAcceptRematch(client *Client, clientID string) {
+	log.Printf("🔁 Client %s accepting a rematch", clientID)
+
+	if err := roomManager.AcceptRematch(clientID); err != nil {
+		log.Printf("Rejected acceptRematch from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// finish records when the server received a player's finishRace
+type finish struct {
+	clientID string
+	at       time.Time
+}
+
+// wpm converts characters typed over a duration to words per minute,
+// counting five characters as one word
+func wpm(chars int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return (float64(chars) / 5.0) / elapsed.Minutes()
+}
+
+// accuracy derives a percentage from the errors a player has
+// left in the characters they have typed
+func accuracy(position, errors int) float64 {
+	if position <= 0 {
+		return 0
+	}
+	if errors > position {
+		errors = position
+	}
+	return float64(position-errors) / float64(position) * 100
+}
+
+// computeResults ranks every player in the room. Finishers are ordered
+// by the time the server received their finish, everyone else by how
+// far they got. Speeds are derived from server timestamps rather than
+// the WPM clients report. The caller must hold rm.mu.
+func computeResults(room *Room, endedAt time.Time) []protocol.RaceResult {
+	textLen := len([]rune(room.passage.Text))
+	results := make([]protocol.RaceResult, 0, len(room.clients))
+	finished := make(map[string]bool, len(room.finishers))
+
+	for _, f := range room.finishers {
+		if _, inRoom := room.clients[f.clientID]; !inRoom {
+			continue
+		}
+		finished[f.clientID] = true
+
+		elapsed := f.at.Sub(room.startedAt)
+		errors := 0
+		if p, exists := room.progress[f.clientID]; exists {
+			errors = p.errors
+		}
+		member := room.players[f.clientID]
+		results = append(results, protocol.RaceResult{
+			PlayerIndex: member.index,
+			Name:        member.name,
+			Color:       member.color,
+			WPM:         wpm(textLen, elapsed),
+			Accuracy:    accuracy(textLen, errors),
+			TimeMs:      elapsed.Milliseconds(),
+			Finished:    true,
+		})
+	}
+
+	var unfinished []protocol.RaceResult
+	positions := make(map[int]int)
+	for clientID := range room.clients {
+		if finished[clientID] {
+			continue
+		}
+		member := room.players[clientID]
+		result := protocol.RaceResult{PlayerIndex: member.index, Name: member.name, Color: member.color}
+		if p, exists := room.progress[clientID]; exists {
+			result.WPM = wpm(p.position, endedAt.Sub(room.startedAt))
+			result.Accuracy = accuracy(p.position, p.errors)
+			positions[result.PlayerIndex] = p.position
+		}
+		unfinished = append(unfinished, result)
+	}
+	sort.Slice(unfinished, func(i, j int) bool {
+		pi, pj := positions[unfinished[i].PlayerIndex], positions[unfinished[j].PlayerIndex]
+		if pi != pj {
+			return pi > pj
+		}
+		return unfinished[i].PlayerIndex < unfinished[j].PlayerIndex
+	})
+
+	results = append(results, unfinished...)
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
+
+// endRace moves the room to the finished phase and broadcasts the
+// final standings. The caller must hold rm.mu.
+func (rm *RoomManager) endRace(roomCode string, room *Room) {
+	room.stopTimer()
+	rm.setPhase(roomCode, room, PhaseFinished)
+
+	results := computeResults(room, time.Now())
+	room.awardPoints(results)
+	if room.ranked {
+		room.rateRace(results)
+	}
+	msg := protocol.RaceResultsResponse{
+		Code: roomCode,
+		Passage: protocol.PassageResponse{
+			ID:     room.passage.ID,
+			Text:   room.passage.Text,
+			Source: room.passage.Source,
+			Author: room.passage.Author,
+		},
+		Results: results,
+	}
+	room.broadcast(msg, "")
+	log.Printf("🏆 Broadcasted results for room %s: %d players ranked", roomCode, len(results))
+
+	// The roster carries the points and ratings just earned
+	rm.broadcastRoomStateLocked(roomCode, room)
+}
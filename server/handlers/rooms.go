@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// MaxPlayers is the most players any room can hold. Hosts may
+// lower the limit for their own room.
+const MaxPlayers = protocol.MaxPlayers
+
+// roomCodeCharset and roomCodeLength describe generated room codes
+const (
+	roomCodeCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	roomCodeLength  = 6
+)
+
+// RoomIdleTimeout is how long a room may go without any of its
+// members sending a message before it is closed
+var RoomIdleTimeout = 30 * time.Minute
+
+var (
+	ErrRoomFull       = errors.New("room is full")
+	ErrRoomLocked     = errors.New("room is locked")
+	ErrRaceInProgress = errors.New("a race is already in progress")
+	ErrWrongPassword  = errors.New("wrong room password")
+)
+
+// errorReasons maps errors clients handle specially to the
+// reason codes sent alongside them
+var errorReasons = map[error]string{
+	ErrRoomNotFound:     protocol.ReasonRoomNotFound,
+	ErrRoomFull:         protocol.ReasonRoomFull,
+	ErrRoomLocked:       protocol.ReasonRoomLocked,
+	ErrRaceInProgress:   protocol.ReasonRaceInProgress,
+	ErrSessionExpired:   protocol.ReasonSessionExpired,
+	ErrUpdateRequired:   protocol.ReasonUpdateRequired,
+	ErrNotHost:          protocol.ReasonNotHost,
+	ErrWrongPassword:    protocol.ReasonWrongPassword,
+	protocol.ErrInvalid: protocol.ReasonInvalidMessage,
+}
+
+// reasonFor returns the reason code for err, which may wrap
+// one of the errors in errorReasons
+func reasonFor(err error) string {
+	for target, reason := range errorReasons {
+		if errors.Is(err, target) {
+			return reason
+		}
+	}
+	return ""
+}
+
+// generateRoomCode returns a random room code. Callers are
+// responsible for checking it against existing rooms.
+func generateRoomCode() string {
+	code := make([]byte, roomCodeLength)
+	max := big.NewInt(int64(len(roomCodeCharset)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		code[i] = roomCodeCharset[n.Int64()]
+	}
+	return string(code)
+}
+
+// hashPassword returns what a room keeps of its password,
+// or nil if it has none
+func hashPassword(password string) []byte {
+	if password == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(password))
+	return sum[:]
+}
+
+// admits reports whether password lets a client into the room.
+// Hashes of equal length are compared in constant time, so how
+// long a guess takes says nothing about the password.
+func (room *Room) admits(password string) bool {
+	if room.password == nil {
+		return true
+	}
+	sum := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(room.password, sum[:]) == 1
+}
+
+// Touch records activity in the client's room, if it is in one
+func (rm *RoomManager) Touch(clientID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, room, err := rm.roomOf(clientID); err == nil {
+		room.lastActive = time.Now()
+	}
+}
+
+// CloseIdleRooms closes every room that has been inactive for longer
+// than RoomIdleTimeout as of now, telling its members why. It returns
+// the number of rooms closed.
+func (rm *RoomManager) CloseIdleRooms(now time.Time) int {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	closed := 0
+	for code, room := range rm.rooms {
+		if now.Sub(room.lastActive) < RoomIdleTimeout {
+			continue
+		}
+
+		room.stopTimer()
+		room.broadcast(protocol.RoomClosedResponse{
+			Code:   code,
+			Reason: protocol.ClosedIdle,
+		}, "")
+		for clientID := range room.clients {
+			if rm.clientToRoom[clientID] == code {
+				delete(rm.clientToRoom, clientID)
+			}
+		}
+		for clientID := range room.spectators {
+			delete(rm.spectating, clientID)
+		}
+		delete(rm.rooms, code)
+		closed++
+		log.Printf("💤 Room %s closed after %s of inactivity", code, RoomIdleTimeout)
+	}
+	return closed
+}
+
+// StartRoomJanitor closes idle rooms every interval until
+// the returned stop function is called
+func StartRoomJanitor(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				roomManager.CloseIdleRooms(now)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
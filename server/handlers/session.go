@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// ResumeGrace is how long a dropped client has to reconnect
+// before its session expires and its room slot is released
+var ResumeGrace = 30 * time.Second
+
+var (
+	ErrSessionExpired = errors.New("session expired or unknown")
+	ErrSessionActive  = errors.New("this connection already holds that session")
+)
+
+// session ties a resumable token to a client identity
+type session struct {
+	clientID string
+	client   *Client // nil while the client is disconnected
+	expiry   *time.Timer
+}
+
+// SessionStore tracks resumable client sessions
+type SessionStore struct {
+	sessions map[string]*session // token -> session
+	onExpire func(clientID string)
+	mu       sync.Mutex
+}
+
+// NewSessionStore creates a session store that calls onExpire
+// when a disconnected session runs out its grace window
+func NewSessionStore(onExpire func(clientID string)) *SessionStore {
+	return &SessionStore{
+		sessions: make(map[string]*session),
+		onExpire: onExpire,
+	}
+}
+
+// newToken returns a random, unguessable session token
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to generate session token: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Create issues a session token for a newly connected client
+func (s *SessionStore) Create(clientID string, client *Client) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := newToken()
+	s.sessions[token] = &session{clientID: clientID, client: client}
+	return token
+}
+
+// Detach marks a session as disconnected and starts its grace window.
+// It does nothing if the session has since been resumed on another
+// connection.
+func (s *SessionStore) Detach(token string, client *Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, exists := s.sessions[token]
+	if !exists || sess.client != client {
+		return
+	}
+
+	sess.client = nil
+	sess.expiry = time.AfterFunc(ResumeGrace, func() {
+		s.expire(token, sess)
+	})
+}
+
+// expire drops a session that was not resumed in time
+func (s *SessionStore) expire(token string, sess *session) {
+	s.mu.Lock()
+	if s.sessions[token] != sess || sess.client != nil {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.sessions, token)
+	s.mu.Unlock()
+
+	log.Printf("⌛ Session for client %s expired", sess.clientID)
+	if s.onExpire != nil {
+		s.onExpire(sess.clientID)
+	}
+}
+
+// Resume attaches client to an existing session, returning its client
+// ID and the connection it replaces, or nil if the session had none
+func (s *SessionStore) Resume(token string, client *Client) (string, *Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, exists := s.sessions[token]
+	if !exists {
+		return "", nil, ErrSessionExpired
+	}
+	if sess.expiry != nil {
+		sess.expiry.Stop()
+		sess.expiry = nil
+	}
+	previous := sess.client
+	sess.client = client
+	return sess.clientID, previous, nil
+}
+
+// Discard removes a session outright
+func (s *SessionStore) Discard(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, exists := s.sessions[token]; exists && sess.expiry != nil {
+		sess.expiry.Stop()
+	}
+	delete(s.sessions, token)
+}
+
+var sessionStore = NewSessionStore(func(clientID string) {
+	roomManager.Expire(clientID)
+})
+
+// Disconnect handles a client's connection closing. Mid-race the
+// player's slot is held so they can resume, though the host role
+// moves on; otherwise they leave their room immediately. Nothing
+// happens if client is no longer the active connection for clientID.
+func (rm *RoomManager) Disconnect(clientID string, client *Client) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.queuedClient(clientID) == client {
+		rm.dequeueLocked(clientID)
+	}
+	if room, exists := rm.rooms[rm.spectating[clientID]]; exists && room.spectators[clientID] == client {
+		rm.stopSpectatingLocked(clientID)
+		return
+	}
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil || room.clients[clientID] != client {
+		return
+	}
+
+	if room.phase == PhaseCountdown || room.phase == PhaseRacing {
+		room.clients[clientID] = nil
+		log.Printf("⏸️ Holding slot for client %s in room %s for %s", clientID, roomCode, ResumeGrace)
+		if room.host == clientID {
+			// Someone still here needs to be able to run the room
+			rm.migrateHostLocked(roomCode, room)
+			rm.broadcastRoomStateLocked(roomCode, room)
+		}
+		return
+	}
+	rm.removeClientLocked(roomCode, clientID)
+}
+
+// Expire releases the slot of a client whose session ran out
+// while they were disconnected
+func (rm *RoomManager) Expire(clientID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil || room.clients[clientID] != nil {
+		return
+	}
+	rm.removeClientLocked(roomCode, clientID)
+}
+
+// Reattach swaps a resumed client's new connection into its room
+// and describes what the client is returning to
+func (rm *RoomManager) Reattach(clientID string, client *Client) protocol.ResumedResponse {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	resp := protocol.ResumedResponse{ClientID: clientID, PlayerIndex: -1}
+	roomCode, room, err := rm.roomOf(clientID)
+	if err != nil {
+		return resp
+	}
+
+	room.clients[clientID] = client
+	resp.Code = roomCode
+	resp.PlayerIndex = room.players[clientID].index
+	resp.Phase = string(room.phase)
+	if room.phase == PhaseCountdown || room.phase == PhaseRacing {
+		resp.Passage = &protocol.PassageResponse{
+			ID:     room.passage.ID,
+			Text:   room.passage.Text,
+			Source: room.passage.Source,
+			Author: room.passage.Author,
+		}
+		if p, exists := room.progress[clientID]; exists {
+			resp.Position = p.position
+		}
+		resp.Finished = room.hasFinished(clientID)
+	}
+	return resp
+}
+
+// handleResume moves this connection onto a previous session.
+// It reports the resumed client ID on success.
+func handleResume(client *Client, clientID, freshToken, token string) (string, bool) {
+	log.Printf("🔁 Client %s attempting to resume a session", clientID)
+
+	// Resuming the session this connection holds would discard it
+	// and then find nothing left to resume
+	if token == freshToken {
+		client.SendError(ErrSessionActive)
+		return "", false
+	}
+
+	resumedID, previous, err := sessionStore.Resume(token, client)
+	if err != nil {
+		log.Printf("Rejected resume from client %s: %v", clientID, err)
+		client.SendError(err)
+		return "", false
+	}
+
+	// The session issued for this connection is no longer needed
+	sessionStore.Discard(freshToken)
+	roomManager.Disconnect(clientID, client)
+
+	resp := roomManager.Reattach(resumedID, client)
+	if previous != nil && previous != client {
+		// Only one connection may act as the client. The old one is
+		// closed once it has been swapped out, so its disconnect
+		// leaves the room alone.
+		previous.Close()
+		log.Printf("🔌 Closed the previous connection of client %s", resumedID)
+	}
+	client.Send(resp)
+	log.Printf("✅ Client %s resumed as %s (room %q)", clientID, resumedID, resp.Code)
+
+	if resp.Code != "" {
+		roomManager.BroadcastRoomState(resp.Code)
+		sendChatHistory(client, resp.Code)
+	}
+	return resumedID, true
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/givensuman/teletyperacer/server/passages"
+)
+
+var (
+	ErrUnknownLanguage = errors.New("no passages are available in that language")
+	ErrTooManyPlayers  = errors.New("the room already has more players than that")
+)
+
+// defaultSettings are the settings new rooms start with
+func defaultSettings() protocol.RoomSettings {
+	return protocol.RoomSettings{
+		MaxPlayers:  MaxPlayers,
+		Punctuation: true,
+		Numbers:     true,
+	}
+}
+
+// countdown returns how long the room counts down before racing
+func (room *Room) countdown() time.Duration {
+	if room.settings.Countdown > 0 {
+		return time.Duration(room.settings.Countdown) * time.Second
+	}
+	return CountdownDuration
+}
+
+// filter narrows a race's passage to the room's settings, unless the
+// request that started it asked for something more specific
+func (room *Room) filter(requested passages.Filter) passages.Filter {
+	if requested.Length == "" {
+		requested.Length = passages.Length(room.settings.Length)
+	}
+	requested.Language = room.settings.Language
+	return requested
+}
+
+// UpdateSettings replaces the settings of the host's room
+func (rm *RoomManager) UpdateSettings(clientID string, settings protocol.RoomSettings) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	roomCode, room, err := rm.hostRoomOf(clientID)
+	if err != nil {
+		return err
+	}
+	if settings.Language != "" && !slices.Contains(passageLibrary.Languages(), settings.Language) {
+		return ErrUnknownLanguage
+	}
+	if settings.MaxPlayers < len(room.players) {
+		return ErrTooManyPlayers
+	}
+
+	room.settings = settings
+	rm.broadcastRoomStateLocked(roomCode, room)
+	return nil
+}
+
+func handleSetSettings(client *Client, clientID string, settings protocol.RoomSettings) {
+	log.Printf("⚙️ Client %s changing room settings to %+v", clientID, settings)
+
+	if err := roomManager.UpdateSettings(clientID, settings); err != nil {
+		log.Printf("Rejected setSettings from client %s: %v", clientID, err)
+		client.SendError(err)
+	}
+}
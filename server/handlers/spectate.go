@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/givensuman/teletyperacer/protocol"
+)
+
+// MaxSpectators is the most clients that can watch a room at once
+const MaxSpectators = 50
+
+// Spectate has a client watch a room without taking a player slot,
+// leaving whatever room it was in. Watching needs the same password
+// as joining. It returns what the client is about to watch.
+func (rm *RoomManager) Spectate(roomCode, clientID string, client *Client, password string) (protocol.SpectatingResponse, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	room, exists := rm.rooms[roomCode]
+	if !exists {
+		return protocol.SpectatingResponse{}, ErrRoomNotFound
+	}
+	if _, watching := room.spectators[clientID]; !watching {
+		if _, member := room.players[clientID]; !member && !room.admits(password) {
+			return protocol.SpectatingResponse{}, ErrWrongPassword
+		}
+		if len(room.spectators) >= MaxSpectators {
+			return protocol.SpectatingResponse{}, ErrRoomFull
+		}
+	}
+
+	rm.leaveLocked(clientID)
+	if _, exists := rm.rooms[roomCode]; !exists {
+		// The client was the last player in the room
+		return protocol.SpectatingResponse{}, ErrRoomNotFound
+	}
+	room.spectators[clientID] = client
+	rm.spectating[clientID] = roomCode
+	log.Printf("👀 Client %s spectating room %s", clientID, roomCode)
+
+	resp := protocol.SpectatingResponse{Code: roomCode, Phase: string(room.phase)}
+	if room.phase == PhaseCountdown || room.phase == PhaseRacing {
+		resp.Passage = &protocol.PassageResponse{
+			ID:     room.passage.ID,
+			Text:   room.passage.Text,
+			Source: room.passage.Source,
+			Author: room.passage.Author,
+		}
+	}
+	return resp, nil
+}
+
+// stopSpectatingLocked stops a client watching its room, if it is
+// watching one. The caller must hold rm.mu.
+func (rm *RoomManager) stopSpectatingLocked(clientID string) {
+	roomCode, watching := rm.spectating[clientID]
+	if !watching {
+		return
+	}
+	delete(rm.spectating, clientID)
+	if room, exists := rm.rooms[roomCode]; exists {
+		delete(room.spectators, clientID)
+		log.Printf("👀 Client %s stopped spectating room %s", clientID, roomCode)
+		rm.broadcastRoomStateLocked(roomCode, room)
+	}
+}
+
+// closeSpectatorsLocked tells a closing room's spectators why, and
+// forgets what they were watching. The caller must hold rm.mu.
+func (rm *RoomManager) closeSpectatorsLocked(roomCode string, room *Room, reason string) {
+	room.broadcastSpectators(protocol.RoomClosedResponse{Code: roomCode, Reason: reason})
+	for clientID := range room.spectators {
+		delete(rm.spectating, clientID)
+	}
+	room.spectators = make(map[string]*Client)
+}
+
+func handleSpectateRoom(client *Client, clientID, code, password string) {
+	log.Printf("👀 Client %s attempting to spectate room %s", clientID, code)
+
+	resp, err := roomManager.Spectate(code, clientID, client, password)
+	if err != nil {
+		log.Printf("Client %s could not spectate room %s: %v", clientID, code, err)
+		client.SendError(err)
+		return
+	}
+	client.Send(resp)
+
+	// Everyone sees the spectator count change
+	roomManager.BroadcastRoomState(code)
+}
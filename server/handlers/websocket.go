@@ -1,12 +1,14 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
-	"github.com/givensuman/teletyperacer/server/types"
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/givensuman/teletyperacer/server/passages"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -17,25 +19,50 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// Message represents a WebSocket message
-type Message struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data,omitempty"`
-}
-
 // Room represents a game room
 type Room struct {
-	clients   map[string]*websocket.Conn
-	indices   map[string]int // clientID -> playerIndex
-	nextIndex int
-	version   int // state version for synchronization
+	clients    map[string]*Client // clientID -> connection, nil while awaiting resume
+	players    map[string]*player // clientID -> roster entry
+	nextIndex  int
+	version    int                        // state version for synchronization
+	host       string                     // clientID of the player allowed to run the room
+	phase      RacePhase                  // current stage of the race lifecycle
+	round      int                        // incremented on every race start to invalidate stale timers
+	rounds     int                        // races completed in the room
+	finishers  []finish                   // players in the order the server saw them finish
+	entrants   []string                   // rating IDs of everyone who started the current race
+	startedAt  time.Time                  // when the current race entered the racing phase
+	timer      *time.Timer                // pending phase transition, if any
+	progress   map[string]*playerProgress // clientID -> latest progress in the current race
+	passage    passages.Passage           // text being raced in the current round
+	locked     bool                       // whether the room refuses new players
+	quick      bool                       // made by the quick-match queue, which fills it
+	ranked     bool                       // made by the ranked queue, whose races change ratings
+	password   []byte                     // SHA-256 of the password needed to enter, nil if none
+	lastActive time.Time                  // when a member last sent a message
+
+	settings    protocol.RoomSettings
+	autoStart   protocol.AutoStart // when the room starts without the host
+	autoStartAt time.Time          // when a pending automatic start fires, zero if none
+
+	chat []protocol.ChatMessage // recent chat, oldest first
+
+	spectators map[string]*Client // clientID -> connection of those watching without a slot
 }
 
 // RoomManager manages WebSocket connections and rooms
 type RoomManager struct {
 	rooms        map[string]*Room  // roomCode -> room
 	clientToRoom map[string]string // clientID -> roomCode
+	spectating   map[string]string // clientID -> roomCode being watched
 	mu           sync.RWMutex
+
+	queue         []queued    // clients waiting for a quick match, longest waiting first
+	queueDeadline time.Time   // when the queue is matched regardless of size, zero if empty
+	queueTimer    *time.Timer // fires at queueDeadline
+
+	rankedQueue []queued    // clients waiting for a ranked match, longest waiting first
+	rankedTimer *time.Timer // matches the ranked queue again, pending while it is not empty
 }
 
 // NewRoomManager creates a new room manager
@@ -43,28 +70,103 @@ func NewRoomManager() *RoomManager {
 	return &RoomManager{
 		rooms:        make(map[string]*Room),
 		clientToRoom: make(map[string]string),
+		spectating:   make(map[string]string),
 	}
 }
 
-// AddClient adds a client to a room
-func (rm *RoomManager) AddClient(roomCode, clientID string, conn *websocket.Conn) {
+// CreateRoom opens a new room under a freshly generated code,
+// with the creating client as its host. The client leaves any
+// room it was already in. Others need the password to enter the
+// room, unless it is empty.
+func (rm *RoomManager) CreateRoom(clientID string, client *Client, name, password string) string {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	if rm.rooms[roomCode] == nil {
-		rm.rooms[roomCode] = &Room{
-			clients:   make(map[string]*websocket.Conn),
-			indices:   make(map[string]int),
-			nextIndex: 0,
-			version:   0,
+	rm.leaveLocked(clientID)
+	code := rm.newRoomLocked(clientID)
+	rm.rooms[code].password = hashPassword(password)
+	rm.addClientLocked(code, clientID, client, name)
+	return code
+}
+
+// newRoomLocked opens an empty room for the given host under a fresh
+// code. The caller must hold rm.mu and add the host to the room.
+func (rm *RoomManager) newRoomLocked(host string) string {
+	code := generateRoomCode()
+	for rm.rooms[code] != nil {
+		code = generateRoomCode()
+	}
+
+	rm.rooms[code] = &Room{
+		clients:    make(map[string]*Client),
+		players:    make(map[string]*player),
+		spectators: make(map[string]*Client),
+		nextIndex:  0,
+		version:    0,
+		host:       host,
+		phase:      PhaseWaiting,
+		progress:   make(map[string]*playerProgress),
+		lastActive: time.Now(),
+		settings:   defaultSettings(),
+		autoStart:  protocol.AutoStart{Delay: DefaultAutoStartDelay},
+	}
+	return code
+}
+
+// JoinRoom adds a client to an existing room, failing if the room
+// does not exist, is not accepting players or needs a different
+// password. Spectators were let in already and need no password.
+func (rm *RoomManager) JoinRoom(roomCode, clientID string, client *Client, name, password string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	room, exists := rm.rooms[roomCode]
+	if !exists {
+		return ErrRoomNotFound
+	}
+	if _, member := room.players[clientID]; !member {
+		_, watching := room.spectators[clientID]
+		switch {
+		case !watching && !room.admits(password):
+			return ErrWrongPassword
+		case room.phase == PhaseCountdown || room.phase == PhaseRacing:
+			return ErrRaceInProgress
+		case room.locked:
+			return ErrRoomLocked
+		case len(room.clients) >= room.settings.MaxPlayers:
+			return ErrRoomFull
 		}
 	}
+
+	// A client belongs to at most one room at a time
+	rm.dequeueLocked(clientID)
+	rm.stopSpectatingLocked(clientID)
+	if current, inRoom := rm.clientToRoom[clientID]; inRoom && current != roomCode {
+		rm.removeClientLocked(current, clientID)
+	}
+	rm.addClientLocked(roomCode, clientID, client, name)
+	return nil
+}
+
+// addClientLocked adds a client to an existing room.
+// The caller must hold rm.mu.
+func (rm *RoomManager) addClientLocked(roomCode, clientID string, client *Client, name string) {
 	room := rm.rooms[roomCode]
-	if _, exists := room.indices[clientID]; !exists {
-		room.indices[clientID] = room.nextIndex
+	if _, exists := room.players[clientID]; !exists {
+		ratingID := client.playerID
+		if ratingID == "" {
+			ratingID = clientID
+		}
+		room.players[clientID] = &player{
+			index:    room.nextIndex,
+			name:     sanitizeName(name, room.nextIndex),
+			color:    room.freeColor(),
+			ratingID: ratingID,
+			rating:   ratingStore.Get(ratingID),
+		}
 		room.nextIndex++
 	}
-	room.clients[clientID] = conn
+	room.clients[clientID] = client
 	rm.clientToRoom[clientID] = roomCode
 }
 
@@ -73,41 +175,72 @@ func (rm *RoomManager) RemoveClient(roomCode, clientID string) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	if room, exists := rm.rooms[roomCode]; exists {
-		delete(room.clients, clientID)
-		delete(room.indices, clientID)
-		delete(rm.clientToRoom, clientID)
-		if len(room.clients) == 0 {
-			delete(rm.rooms, roomCode)
-		} else {
-			// Broadcast updated state to remaining clients
-			rm.BroadcastRoomState(roomCode)
-		}
-	}
+	rm.removeClientLocked(roomCode, clientID)
 }
 
-// BroadcastToRoom broadcasts a message to all clients in a room except the sender
-func (rm *RoomManager) BroadcastToRoom(roomCode, senderID string, msg interface{}) {
-	rm.mu.RLock()
-	defer rm.mu.RUnlock()
-
+// removeClientLocked removes a client from a room, deleting the room
+// once it is empty. The caller must hold rm.mu.
+func (rm *RoomManager) removeClientLocked(roomCode, clientID string) {
 	room, exists := rm.rooms[roomCode]
 	if !exists {
 		return
 	}
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling broadcast message: %v", err)
+	if room.host == clientID {
+		rm.migrateHostLocked(roomCode, room)
+	}
+	delete(room.clients, clientID)
+	delete(room.players, clientID)
+	delete(room.progress, clientID)
+	if rm.clientToRoom[clientID] == roomCode {
+		delete(rm.clientToRoom, clientID)
+	}
+	log.Printf("👋 Client %s removed from room %s", clientID, roomCode)
+
+	if len(room.clients) == 0 {
+		room.stopTimer()
+		rm.closeSpectatorsLocked(roomCode, room, protocol.ClosedEmpty)
+		delete(rm.rooms, roomCode)
+		log.Printf("🧹 Room %s is empty and has been closed", roomCode)
 		return
 	}
 
-	for clientID, conn := range room.clients {
-		if clientID != senderID {
-			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-				log.Printf("Error broadcasting to client %s: %v", clientID, err)
-			}
-		}
+	// The race may have been waiting on the player who left
+	if room.phase == PhaseRacing && room.allFinished() {
+		rm.endRace(roomCode, room)
+	}
+
+	// Broadcast updated state to remaining clients
+	rm.broadcastRoomStateLocked(roomCode, room)
+}
+
+// LeaveRoom removes a client from whichever room it is in.
+// It does nothing if the client is not in a room.
+func (rm *RoomManager) LeaveRoom(clientID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.leaveLocked(clientID)
+}
+
+// leaveLocked removes a client from its current room, if any, or
+// the quick-match queue, or stops it spectating. The caller must
+// hold rm.mu.
+func (rm *RoomManager) leaveLocked(clientID string) {
+	rm.dequeueLocked(clientID)
+	rm.stopSpectatingLocked(clientID)
+	if roomCode, inRoom := rm.clientToRoom[clientID]; inRoom {
+		rm.removeClientLocked(roomCode, clientID)
+	}
+}
+
+// BroadcastToRoom broadcasts a message to all clients in a room except the sender
+func (rm *RoomManager) BroadcastToRoom(roomCode, senderID string, msg protocol.Message) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	if room, exists := rm.rooms[roomCode]; exists {
+		room.broadcast(msg, senderID)
 	}
 }
 
@@ -128,38 +261,84 @@ func (rm *RoomManager) GetPlayerIndex(roomCode, clientID string) int {
 	defer rm.mu.RUnlock()
 
 	if room, exists := rm.rooms[roomCode]; exists {
-		if index, exists := room.indices[clientID]; exists {
-			return index
+		if p, exists := room.players[clientID]; exists {
+			return p.index
 		}
 	}
 	return -1
 }
 
-// BroadcastRoomState sends updated roomState to all clients in the room
-func (rm *RoomManager) BroadcastRoomState(roomCode string) {
+// RoomState returns a consistent snapshot of a room as seen by one of
+// its clients, or false if the client is not in the room
+func (rm *RoomManager) RoomState(roomCode, clientID string) (protocol.RoomStateResponse, bool) {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
+	room, exists := rm.rooms[roomCode]
+	if !exists {
+		return protocol.RoomStateResponse{}, false
+	}
+	if p, member := room.players[clientID]; member {
+		return room.stateFor(roomCode, p.index), true
+	}
+	if _, spectating := room.spectators[clientID]; spectating {
+		return room.stateFor(roomCode, -1), true
+	}
+	return protocol.RoomStateResponse{}, false
+}
+
+// stateFor describes the room as seen by the player with the given
+// roster index, or by a spectator if index is -1. The caller must
+// hold rm.mu.
+func (room *Room) stateFor(roomCode string, index int) protocol.RoomStateResponse {
+	return protocol.RoomStateResponse{
+		Code:        roomCode,
+		PlayerCount: len(room.clients),
+		YourIndex:   index,
+		Version:     room.version,
+		Phase:       string(room.phase),
+		Players:     room.roster(),
+		Locked:      room.locked,
+		AutoStart:   room.autoStart,
+		Settings:    room.settings,
+		StartingIn:  room.startingIn(),
+		Rounds:      room.rounds,
+		Spectators:  len(room.spectators),
+		Spectating:  index < 0,
+	}
+}
+
+// BroadcastRoomState sends updated roomState to all clients in the room
+func (rm *RoomManager) BroadcastRoomState(roomCode string) {
+	// Broadcasting bumps the room's version, so this needs the write lock
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
 	room, exists := rm.rooms[roomCode]
 	if !exists {
 		return
 	}
+	rm.broadcastRoomStateLocked(roomCode, room)
+}
+
+// broadcastRoomStateLocked sends roomState to every client in room,
+// spectators included.
+// Every roster change is broadcast, so this is also where a pending
+// automatic start is scheduled or cancelled. The caller must hold
+// rm.mu for writing.
+func (rm *RoomManager) broadcastRoomStateLocked(roomCode string, room *Room) {
+	rm.scheduleAutoStartLocked(roomCode, room)
 
-	playerCount := len(room.clients)
 	room.version++
 
-	for clientID, conn := range room.clients {
-		yourIndex := room.indices[clientID]
-		roomState := types.RoomStateResponse{
-			Code:        roomCode,
-			PlayerCount: playerCount,
-			YourIndex:   yourIndex,
-			Version:     room.version,
-		}
-		stateMsg := Message{Type: "roomState", Data: roomState}
-		sendMessage(conn, stateMsg)
+	for clientID, client := range room.clients {
+		roomState := room.stateFor(roomCode, room.players[clientID].index)
+		client.Send(roomState)
 		log.Printf("📤 Broadcasted roomState to client %s for room %s: %d players, yourIndex %d, version %d", clientID, roomCode, roomState.PlayerCount, roomState.YourIndex, roomState.Version)
 	}
+	if len(room.spectators) > 0 {
+		room.broadcastSpectators(room.stateFor(roomCode, -1))
+	}
 }
 
 var roomManager = NewRoomManager()
@@ -171,11 +350,27 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to upgrade connection: %v", err)
 		return
 	}
-	defer conn.Close()
+	client := newClient(conn)
+	defer client.Close()
 
 	clientID := uuid.New().String()
 	log.Printf("🔌 New WebSocket connection established - Client ID: %s", clientID)
 
+	welcomed := awaitHello(client, clientID)
+	client.start()
+	if !welcomed {
+		// Let the rejection reach the client before hanging up
+		client.CloseAfterQueued(websocket.ClosePolicyViolation, "update required")
+		<-client.done
+		return
+	}
+
+	// Issue a session token the client can use to resume after a drop
+	token := sessionStore.Create(clientID, client)
+	if client.Supports(protocol.CapabilityResume) {
+		client.Send(protocol.SessionResponse{ClientID: clientID, Token: token})
+	}
+
 	// Handle messages from this client
 	for {
 		_, data, err := conn.ReadMessage()
@@ -183,103 +378,151 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			log.Printf("WebSocket read error for client %s: %v", clientID, err)
 			break
 		}
+		client.extendReadDeadline()
+		roomManager.Touch(clientID)
 
-		var msg Message
-		if err := json.Unmarshal(data, &msg); err != nil {
-			log.Printf("Error unmarshaling message from client %s: %v", clientID, err)
+		msg, err := protocol.Decode(data)
+		if errors.Is(err, protocol.ErrUnknownType) {
+			log.Printf("Unknown message from client %s: %v", clientID, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("Rejected message from client %s: %v", clientID, err)
+			client.SendError(err)
 			continue
 		}
 
-		switch msg.Type {
-		case "createRoom":
-			var req types.CreateRoomRequest
-			if dataBytes, err := json.Marshal(msg.Data); err == nil {
-				json.Unmarshal(dataBytes, &req)
-			}
-			handleCreateRoom(conn, clientID, req.Code)
+		switch req := msg.(type) {
+		case protocol.CreateRoomRequest:
+			handleCreateRoom(client, clientID, req.Name, req.Password)
 
-		case "joinRoom":
-			var req types.JoinRoomRequest
-			if dataBytes, err := json.Marshal(msg.Data); err == nil {
-				json.Unmarshal(dataBytes, &req)
-			}
-			handleJoinRoom(conn, clientID, req.Code)
+		case protocol.JoinRoomRequest:
+			handleJoinRoom(client, clientID, req.Code, req.Name, req.Password)
+
+		case protocol.LeaveRoomRequest:
+			handleLeaveRoom(clientID)
+
+		case protocol.SpectateRoomRequest:
+			handleSpectateRoom(client, clientID, req.Code, req.Password)
+
+		case protocol.ListRoomsRequest:
+			handleListRooms(client, clientID)
 
-		case "getRoomState":
-			var req types.JoinRoomRequest // reuse for code
-			if dataBytes, err := json.Marshal(msg.Data); err == nil {
-				json.Unmarshal(dataBytes, &req)
+		case protocol.QuickMatchRequest:
+			handleQuickMatch(client, clientID, req)
+
+		case protocol.StartRaceRequest:
+			handleStartRace(client, clientID, req)
+
+		case protocol.FinishRaceRequest:
+			handleFinishRace(client, clientID)
+
+		case protocol.VoteRematchRequest:
+			handleVoteRematch(client, clientID, req.Vote)
+
+		case protocol.AcceptRematchRequest:
+			handleAcceptRematch(client, clientID)
+
+		case protocol.ProgressRequest:
+			handleProgress(client, clientID, req)
+
+		case protocol.ResumeRequest:
+			if resumedID, ok := handleResume(client, clientID, token, req.Token); ok {
+				clientID, token = resumedID, req.Token
 			}
-			handleGetRoomState(conn, clientID, req.Code)
+
+		case protocol.ChatRequest:
+			handleChat(client, clientID, req.Text)
+
+		case protocol.SetSettingsRequest:
+			handleSetSettings(client, clientID, req.Settings)
+
+		case protocol.SetReadyRequest:
+			handleSetReady(client, clientID, req.Ready)
+
+		case protocol.SetAutoStartRequest:
+			handleSetAutoStart(client, clientID, req.AutoStart)
+
+		case protocol.KickPlayerRequest:
+			handleKickPlayer(client, clientID, req.PlayerIndex)
+
+		case protocol.LockRoomRequest:
+			handleLockRoom(client, clientID, req.Locked)
+
+		case protocol.TransferHostRequest:
+			handleTransferHost(client, clientID, req.PlayerIndex)
+
+		case protocol.GetRoomStateRequest:
+			handleGetRoomState(client, clientID, req.Code)
 
 		default:
-			log.Printf("Unknown message type from client %s: %s", clientID, msg.Type)
+			log.Printf("Unexpected %s message from client %s", msg.MessageType(), clientID)
 		}
 	}
 
 	// Clean up when client disconnects
 	log.Printf("🔌 WebSocket connection closed - Client ID: %s disconnected", clientID)
-	// Note: In a real implementation, you'd need to track which rooms the client was in
-	// For simplicity, we'll assume clients are only in one room at a time
+	sessionStore.Detach(token, client)
+	roomManager.Disconnect(clientID, client)
 }
 
-func handleCreateRoom(conn *websocket.Conn, clientID, code string) {
-	log.Printf("🏠 Client %s attempting to create room with code %s", clientID, code)
+func handleCreateRoom(client *Client, clientID, name, password string) {
+	log.Printf("🏠 Client %s attempting to create a room", clientID)
 
-	roomManager.AddClient(code, clientID, conn)
+	code := roomManager.CreateRoom(clientID, client, name, password)
 	log.Printf("✅ Room %s created successfully by client %s", code, clientID)
 
 	// Send room created confirmation
-	response := Message{Type: "roomCreated", Data: types.RoomCreatedResponse{Code: code}}
-	sendMessage(conn, response)
+	response := protocol.RoomCreatedResponse{Code: code}
+	client.Send(response)
 	log.Printf("📤 Sent roomCreated confirmation to client %s for room %s", clientID, code)
 
 	// Broadcast initial room state to all (just the host)
 	roomManager.BroadcastRoomState(code)
 }
 
-func handleJoinRoom(conn *websocket.Conn, clientID, code string) {
+func handleJoinRoom(client *Client, clientID, code, name, password string) {
 	log.Printf("🚪 Client %s attempting to join room %s", clientID, code)
 
-	roomManager.AddClient(code, clientID, conn)
+	err := roomManager.JoinRoom(code, clientID, client, name, password)
+	if errors.Is(err, ErrRaceInProgress) {
+		// Watch the race under way instead, and join after it
+		handleSpectateRoom(client, clientID, code, password)
+		return
+	}
+	if err != nil {
+		log.Printf("Client %s could not join room %s: %v", clientID, code, err)
+		client.SendError(err)
+		return
+	}
 	log.Printf("✅ Client %s successfully joined room %s", clientID, code)
 
 	// Send join confirmation
-	response := Message{Type: "roomJoined", Data: types.RoomJoinedResponse{Code: code}}
-	sendMessage(conn, response)
+	response := protocol.RoomJoinedResponse{Code: code}
+	client.Send(response)
 	log.Printf("📤 Sent roomJoined confirmation to client %s for room %s", clientID, code)
 
 	// Broadcast updated room state to all clients
 	roomManager.BroadcastRoomState(code)
+	sendChatHistory(client, code)
+}
+
+func handleLeaveRoom(clientID string) {
+	log.Printf("🚶 Client %s leaving its room", clientID)
+
+	roomManager.LeaveRoom(clientID)
 }
 
-func handleGetRoomState(conn *websocket.Conn, clientID, code string) {
+func handleGetRoomState(client *Client, clientID, code string) {
 	log.Printf("📥 Client %s requesting room state for room %s", clientID, code)
 
-	playerCount := roomManager.GetRoomClients(code)
-	yourIndex := roomManager.GetPlayerIndex(code, clientID)
-	if yourIndex == -1 {
+	roomState, ok := roomManager.RoomState(code, clientID)
+	if !ok {
 		log.Printf("Client %s not in room %s", clientID, code)
 		return
 	}
 
-	roomState := types.RoomStateResponse{
-		Code:        code,
-		PlayerCount: playerCount,
-		YourIndex:   yourIndex,
-	}
-	stateMsg := Message{Type: "roomState", Data: roomState}
-	sendMessage(conn, stateMsg)
+	stateMsg := roomState
+	client.Send(stateMsg)
 	log.Printf("📤 Sent roomState to client %s for room %s: %d players, yourIndex %d", clientID, code, roomState.PlayerCount, roomState.YourIndex)
 }
-
-func sendMessage(conn *websocket.Conn, msg interface{}) {
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
-		return
-	}
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		log.Printf("Error sending message: %v", err)
-	}
-}
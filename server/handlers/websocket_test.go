@@ -0,0 +1,1425 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/givensuman/teletyperacer/protocol"
+	"github.com/givensuman/teletyperacer/server/ratings"
+	"github.com/gorilla/websocket"
+)
+
+// testClient wraps a websocket connection to a test server
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	// Handlers from earlier tests may still be winding down, so the
+	// shared manager is emptied in place rather than replaced
+	roomManager.mu.Lock()
+	roomManager.rooms = make(map[string]*Room)
+	roomManager.clientToRoom = make(map[string]string)
+	roomManager.spectating = make(map[string]string)
+	roomManager.queue = nil
+	roomManager.rankedQueue = nil
+	roomManager.stopQueueTimerLocked()
+	roomManager.mu.Unlock()
+	ratingStore = ratings.NewStore()
+	srv := httptest.NewServer(http.HandlerFunc(HandleWebSocket))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dialRaw connects to the test server without handshaking
+func dialRaw(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn}
+}
+
+// dial connects to the test server as a current client
+func dial(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+	c := dialRaw(t, srv)
+	c.send(protocol.HelloRequest{ProtocolVersion: protocol.Version, Capabilities: Capabilities})
+	c.expect("welcome", nil)
+	return c
+}
+
+func (c *testClient) send(msg protocol.Message) {
+	c.t.Helper()
+	data, err := protocol.Encode(msg)
+	if err != nil {
+		c.t.Fatalf("encode %s: %v", msg.MessageType(), err)
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		c.t.Fatalf("send %s: %v", msg.MessageType(), err)
+	}
+}
+
+// createRoom asks the server for a new room and returns its code
+func (c *testClient) createRoom() string {
+	c.t.Helper()
+	c.send(protocol.CreateRoomRequest{})
+	var created struct {
+		Code string `json:"code"`
+	}
+	c.expect("roomCreated", &created)
+	return created.Code
+}
+
+// backdateRace moves the start of the room's race far enough into the
+// past that the whole passage could have been typed, and returns the
+// passage length
+func backdateRace(code string) int {
+	roomManager.mu.Lock()
+	defer roomManager.mu.Unlock()
+	room := roomManager.rooms[code]
+	length := len([]rune(room.passage.Text))
+	took := time.Duration(float64(length) / MaxTypingSpeed * float64(time.Second))
+	if start := time.Now().Add(-took); room.startedAt.After(start) {
+		room.startedAt = start
+	}
+	return length
+}
+
+// finishRace types out the passage being raced in the room, then
+// reports the finish
+func (c *testClient) finishRace(code string) {
+	c.t.Helper()
+	length := backdateRace(code)
+	c.send(protocol.ProgressRequest{Position: length})
+	c.send(protocol.FinishRaceRequest{})
+}
+
+// expect reads messages until one of the given type arrives and
+// decodes its data into v
+func (c *testClient) expect(msgType string, v interface{}) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if msg.Type != msgType {
+			continue
+		}
+		if v != nil {
+			if err := json.Unmarshal(msg.Data, v); err != nil {
+				c.t.Fatalf("decoding %s: %v", msgType, err)
+			}
+		}
+		return
+	}
+}
+
+func TestRaceLifecycle(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = 50 * time.Millisecond
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	// Only the host may start, and finishing before racing is rejected
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	guest.send(protocol.StartRaceRequest{})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrNotHost.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotHost, errResp.Message)
+	}
+	guest.send(protocol.FinishRaceRequest{})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrNotRacing.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotRacing, errResp.Message)
+	}
+
+	host.send(protocol.StartRaceRequest{})
+	for _, want := range []RacePhase{PhaseCountdown, PhaseRacing} {
+		var phase struct {
+			Phase   string `json:"phase"`
+			Passage *struct {
+				Text string `json:"text"`
+			} `json:"passage"`
+		}
+		guest.expect("racePhase", &phase)
+		if phase.Phase != string(want) {
+			t.Fatalf("expected phase %s, got %s", want, phase.Phase)
+		}
+		if want == PhaseCountdown && (phase.Passage == nil || phase.Passage.Text == "") {
+			t.Fatal("expected countdown to carry the race passage")
+		}
+	}
+
+	host.send(protocol.StartRaceRequest{})
+	host.expect("error", &errResp)
+	if errResp.Message != ErrRaceStarted.Error() {
+		t.Fatalf("expected %q, got %q", ErrRaceStarted, errResp.Message)
+	}
+
+	// A finish only counts once the server has seen the whole passage typed
+	guest.send(protocol.FinishRaceRequest{})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrNotComplete.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotComplete, errResp.Message)
+	}
+
+	// Nor does one typed faster than anyone could
+	roomManager.mu.RLock()
+	length := len([]rune(roomManager.rooms[code].passage.Text))
+	roomManager.mu.RUnlock()
+	guest.send(protocol.ProgressRequest{Position: length})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrTooFast.Error() {
+		t.Fatalf("expected %q, got %q", ErrTooFast, errResp.Message)
+	}
+	guest.send(protocol.FinishRaceRequest{})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrNotComplete.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotComplete, errResp.Message)
+	}
+
+	guest.finishRace(code)
+	host.expect("playerFinished", nil)
+	host.finishRace(code)
+	var phase struct {
+		Phase string `json:"phase"`
+	}
+	host.expect("racePhase", &phase)
+	if phase.Phase != string(PhaseFinished) {
+		t.Fatalf("expected phase %s, got %s", PhaseFinished, phase.Phase)
+	}
+
+	// The guest's finish reached the server first, so they win
+	var results struct {
+		Results []struct {
+			PlayerIndex int  `json:"playerIndex"`
+			Rank        int  `json:"rank"`
+			Finished    bool `json:"finished"`
+		} `json:"results"`
+	}
+	host.expect("raceResults", &results)
+	if len(results.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results.Results))
+	}
+	if first := results.Results[0]; first.PlayerIndex != 1 || first.Rank != 1 || !first.Finished {
+		t.Fatalf("expected guest to place first, got %+v", first)
+	}
+}
+
+func TestProgressRelay(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = 10 * time.Millisecond
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	host.send(protocol.StartRaceRequest{})
+	for i := 0; i < 2; i++ {
+		guest.expect("racePhase", nil)
+	}
+
+	guest.send(protocol.ProgressRequest{Position: 12, WPM: 80.5, Errors: 1})
+	var progress struct {
+		PlayerIndex int     `json:"playerIndex"`
+		Position    int     `json:"position"`
+		WPM         float64 `json:"wpm"`
+		Errors      int     `json:"errors"`
+	}
+	host.expect("playerProgress", &progress)
+	if progress.PlayerIndex != 1 || progress.Position != 12 || progress.WPM != 80.5 || progress.Errors != 1 {
+		t.Fatalf("unexpected progress relayed: %+v", progress)
+	}
+
+	// Positions stop at the end of the passage, and nothing more is
+	// relayed from a player who has reached it
+	length := backdateRace(code)
+	guest.send(protocol.ProgressRequest{Position: length * 10, WPM: 90})
+	host.expect("playerProgress", &progress)
+	if progress.Position != length {
+		t.Fatalf("expected position clamped to %d, got %d", length, progress.Position)
+	}
+	guest.send(protocol.ProgressRequest{Position: length * 10, WPM: 90})
+	guest.send(protocol.FinishRaceRequest{})
+	var next struct {
+		Type string `json:"type"`
+	}
+	host.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := host.conn.ReadJSON(&next); err != nil || next.Type != "playerFinished" {
+		t.Fatalf("expected playerFinished, got %q (%v)", next.Type, err)
+	}
+}
+
+func TestDisconnectCleanup(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	var state struct {
+		PlayerCount int `json:"playerCount"`
+	}
+	for state.PlayerCount != 2 {
+		host.expect("roomState", &state)
+	}
+	guest.conn.Close()
+	for state.PlayerCount != 1 {
+		host.expect("roomState", &state)
+	}
+	if n := roomManager.GetRoomClients(code); n != 1 {
+		t.Fatalf("expected 1 client left in room, got %d", n)
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = 10 * time.Millisecond
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	guest := dial(t, srv)
+	var sess struct {
+		ClientID string `json:"clientId"`
+		Token    string `json:"token"`
+	}
+	guest.expect("session", &sess)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	host.send(protocol.StartRaceRequest{})
+	for i := 0; i < 2; i++ {
+		guest.expect("racePhase", nil)
+	}
+	guest.send(protocol.ProgressRequest{Position: 5, WPM: 60})
+	host.expect("playerProgress", nil)
+	guest.conn.Close()
+
+	// Mid-race, the dropped guest keeps their slot
+	time.Sleep(50 * time.Millisecond)
+	if n := roomManager.GetRoomClients(code); n != 2 {
+		t.Fatalf("expected the slot to be held, got %d clients", n)
+	}
+
+	again := dial(t, srv)
+	again.send(protocol.ResumeRequest{Token: sess.Token})
+	var resumed struct {
+		ClientID    string `json:"clientId"`
+		Code        string `json:"code"`
+		PlayerIndex int    `json:"playerIndex"`
+		Phase       string `json:"phase"`
+		Position    int    `json:"position"`
+	}
+	again.expect("resumed", &resumed)
+	if resumed.ClientID != sess.ClientID || resumed.Code != code || resumed.PlayerIndex != 1 ||
+		resumed.Phase != string(PhaseRacing) || resumed.Position != 5 {
+		t.Fatalf("unexpected resume state: %+v", resumed)
+	}
+
+	again.send(protocol.ResumeRequest{Token: "bogus"})
+	again.expect("error", nil)
+
+	// Resuming the session the connection already holds is refused,
+	// and leaves the session in place to be resumed below
+	again.send(protocol.ResumeRequest{Token: sess.Token})
+	var errResp protocol.ErrorResponse
+	again.expect("error", &errResp)
+	if errResp.Message != ErrSessionActive.Error() {
+		t.Fatalf("expected %q, got %q", ErrSessionActive, errResp.Message)
+	}
+
+	// Resuming on another connection closes the one still attached
+	third := dial(t, srv)
+	third.send(protocol.ResumeRequest{Token: sess.Token})
+	third.expect("resumed", &resumed)
+	again.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := again.conn.ReadMessage(); err != nil {
+			if strings.Contains(err.Error(), "timeout") {
+				t.Fatalf("expected the old connection to be closed, got %v", err)
+			}
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := roomManager.GetRoomClients(code); n != 2 {
+		t.Fatalf("expected the slot to survive the old connection closing, got %d clients", n)
+	}
+	third.send(protocol.ProgressRequest{Position: 8, WPM: 60})
+	var progress protocol.PlayerProgressResponse
+	host.expect("playerProgress", &progress)
+	if progress.PlayerIndex != 1 || progress.Position != 8 {
+		t.Fatalf("expected progress from the resumed player, got %+v", progress)
+	}
+}
+
+func TestJoinRoomRejections(t *testing.T) {
+	srv := newTestServer(t)
+
+	var errResp struct {
+		Reason string `json:"reason"`
+	}
+	lost := dial(t, srv)
+	lost.send(protocol.JoinRoomRequest{Code: "NOPE00"})
+	lost.expect("error", &errResp)
+	if errResp.Reason != "roomNotFound" {
+		t.Fatalf("expected roomNotFound, got %q", errResp.Reason)
+	}
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	if len(code) != roomCodeLength {
+		t.Fatalf("expected a %d character code, got %q", roomCodeLength, code)
+	}
+	for i := 1; i < MaxPlayers; i++ {
+		c := dial(t, srv)
+		c.send(protocol.JoinRoomRequest{Code: code})
+		c.expect("roomJoined", nil)
+	}
+
+	late := dial(t, srv)
+	late.send(protocol.JoinRoomRequest{Code: code})
+	late.expect("error", &errResp)
+	if errResp.Reason != "roomFull" {
+		t.Fatalf("expected roomFull, got %q", errResp.Reason)
+	}
+}
+
+func TestDeadPeerDropped(t *testing.T) {
+	pingPeriod, pongWait := PingPeriod, PongWait
+	PingPeriod, PongWait = 20*time.Millisecond, 200*time.Millisecond
+	t.Cleanup(func() { PingPeriod, PongWait = pingPeriod, pongWait })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	// The guest joins and then stops reading, so it never answers pings
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	var state struct {
+		PlayerCount int `json:"playerCount"`
+	}
+	for state.PlayerCount != 2 {
+		host.expect("roomState", &state)
+	}
+
+	// The host keeps answering pings while it waits, so only the
+	// guest should be dropped
+	for state.PlayerCount != 1 {
+		host.expect("roomState", &state)
+	}
+	if n := roomManager.GetRoomClients(code); n != 1 {
+		t.Fatalf("expected 1 client left in room, got %d", n)
+	}
+}
+
+func TestIdleRoomsClosed(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	if n := roomManager.CloseIdleRooms(time.Now()); n != 0 {
+		t.Fatalf("expected active room to stay open, closed %d", n)
+	}
+	if n := roomManager.CloseIdleRooms(time.Now().Add(RoomIdleTimeout)); n != 1 {
+		t.Fatalf("expected idle room to close, closed %d", n)
+	}
+
+	var closed struct {
+		Code   string `json:"code"`
+		Reason string `json:"reason"`
+	}
+	host.expect("roomClosed", &closed)
+	if closed.Code != code || closed.Reason != "idle" {
+		t.Fatalf("unexpected roomClosed %+v", closed)
+	}
+
+	var errResp struct {
+		Reason string `json:"reason"`
+	}
+	host.send(protocol.JoinRoomRequest{Code: code})
+	host.expect("error", &errResp)
+	if errResp.Reason != "roomNotFound" {
+		t.Fatalf("expected roomNotFound, got %q", errResp.Reason)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	srv := newTestServer(t)
+
+	// Capabilities the server does not know about are not enabled
+	c := dialRaw(t, srv)
+	c.send(protocol.HelloRequest{ProtocolVersion: protocol.Version, Capabilities: []string{"resume", "telepathy"}})
+	var welcome protocol.WelcomeResponse
+	c.expect("welcome", &welcome)
+	if welcome.ProtocolVersion != protocol.Version {
+		t.Fatalf("expected protocol v%d, got v%d", protocol.Version, welcome.ProtocolVersion)
+	}
+	if len(welcome.Capabilities) != 1 || welcome.Capabilities[0] != "resume" {
+		t.Fatalf("expected only resume to be enabled, got %v", welcome.Capabilities)
+	}
+	c.expect("session", nil)
+
+	// Newer clients are asked to speak the server's version
+	newer := dialRaw(t, srv)
+	newer.send(protocol.HelloRequest{ProtocolVersion: protocol.Version + 1})
+	newer.expect("welcome", &welcome)
+	if welcome.ProtocolVersion != protocol.Version {
+		t.Fatalf("expected protocol v%d, got v%d", protocol.Version, welcome.ProtocolVersion)
+	}
+
+	// Old protocol versions, and clients that skip the handshake
+	// entirely, are told to update and then disconnected
+	for name, first := range map[string]protocol.Message{
+		"old protocol": protocol.HelloRequest{ProtocolVersion: protocol.MinVersion - 1},
+		"no hello":     protocol.CreateRoomRequest{},
+	} {
+		c := dialRaw(t, srv)
+		c.send(first)
+		var errResp protocol.ErrorResponse
+		c.expect("error", &errResp)
+		if errResp.Reason != "updateRequired" {
+			t.Fatalf("%s: expected updateRequired, got %q", name, errResp.Reason)
+		}
+		if _, _, err := c.conn.ReadMessage(); !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+			t.Fatalf("%s: expected policy violation close, got %v", name, err)
+		}
+	}
+}
+
+func TestNoCapabilities(t *testing.T) {
+	countdown, pongWait := CountdownDuration, PongWait
+	CountdownDuration = 10 * time.Millisecond
+	t.Cleanup(func() { CountdownDuration, PongWait = countdown, pongWait })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+
+	// A client that enables nothing can still race to the finish
+	bare := dialRaw(t, srv)
+	bare.send(protocol.HelloRequest{ProtocolVersion: protocol.Version})
+	bare.expect("welcome", nil)
+	bare.send(protocol.JoinRoomRequest{Code: code})
+	bare.expect("roomJoined", nil)
+
+	host.send(protocol.StartRaceRequest{})
+	for i := 0; i < 2; i++ {
+		bare.expect("racePhase", nil)
+	}
+	bare.finishRace(code)
+	host.expect("playerFinished", nil)
+	host.finishRace(code)
+	var results struct {
+		Results []struct {
+			PlayerIndex int  `json:"playerIndex"`
+			Finished    bool `json:"finished"`
+		} `json:"results"`
+	}
+	bare.expect("raceResults", &results)
+	if len(results.Results) != 2 || results.Results[0].PlayerIndex != 1 || !results.Results[0].Finished {
+		t.Fatalf("expected the bare client to finish first, got %+v", results.Results)
+	}
+
+	// It is not pinged, but is still dropped once it goes quiet
+	PongWait = 100 * time.Millisecond
+	quiet := dialRaw(t, srv)
+	quiet.send(protocol.HelloRequest{ProtocolVersion: protocol.Version})
+	quiet.expect("welcome", nil)
+	quiet.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := quiet.conn.ReadMessage(); err != nil {
+			if strings.Contains(err.Error(), "timeout") {
+				t.Fatalf("expected the quiet client to be dropped, got %v", err)
+			}
+			break
+		}
+	}
+}
+func TestInvalidMessages(t *testing.T) {
+	srv := newTestServer(t)
+	c := dial(t, srv)
+
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"joinRoom","data":{"code":""}}`)); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	var errResp protocol.ErrorResponse
+	c.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonInvalidMessage {
+		t.Fatalf("expected %s, got %q", protocol.ReasonInvalidMessage, errResp.Reason)
+	}
+}
+
+func TestRoster(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	host.send(protocol.CreateRoomRequest{Name: "  Ada\x07 "})
+	var created protocol.RoomCreatedResponse
+	host.expect("roomCreated", &created)
+	code := created.Code
+
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	var state protocol.RoomStateResponse
+	for len(state.Players) != 2 {
+		guest.expect("roomState", &state)
+	}
+	want := []protocol.Player{
+		{Index: 0, Name: "Ada", Color: 0, Host: true, Rating: ratings.Initial},
+		{Index: 1, Name: "Player 2", Color: 1, Rating: ratings.Initial},
+	}
+	if !reflect.DeepEqual(state.Players, want) || state.YourIndex != 1 {
+		t.Fatalf("unexpected roster %+v (yourIndex %d)", state.Players, state.YourIndex)
+	}
+
+	// A later player gets a fresh index but reuses the free color
+	guest.conn.Close()
+	for len(state.Players) != 1 {
+		host.expect("roomState", &state)
+	}
+	late := dial(t, srv)
+	late.send(protocol.JoinRoomRequest{Code: code, Name: "Grace"})
+	for len(state.Players) != 2 {
+		late.expect("roomState", &state)
+	}
+	if p := state.Players[1]; p.Index != 2 || p.Color != 1 || p.Name != "Grace" || state.YourIndex != 2 {
+		t.Fatalf("unexpected late player %+v (yourIndex %d)", p, state.YourIndex)
+	}
+}
+
+func TestHostControls(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	var errResp protocol.ErrorResponse
+	guest.send(protocol.LockRoomRequest{Locked: true})
+	guest.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonNotHost {
+		t.Fatalf("expected notHost, got %q", errResp.Reason)
+	}
+
+	// Locking keeps newcomers out
+	host.send(protocol.LockRoomRequest{Locked: true})
+	var state protocol.RoomStateResponse
+	for !state.Locked {
+		guest.expect("roomState", &state)
+	}
+	late := dial(t, srv)
+	late.send(protocol.JoinRoomRequest{Code: code})
+	late.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonRoomLocked {
+		t.Fatalf("expected roomLocked, got %q", errResp.Reason)
+	}
+
+	// Handing over the room makes the guest the only one in charge
+	host.send(protocol.TransferHostRequest{PlayerIndex: 1})
+	for !state.Players[1].Host {
+		guest.expect("roomState", &state)
+	}
+	if state.Players[0].Host {
+		t.Fatalf("expected a single host, got %+v", state.Players)
+	}
+	host.send(protocol.KickPlayerRequest{PlayerIndex: 1})
+	host.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonNotHost {
+		t.Fatalf("expected notHost, got %q", errResp.Reason)
+	}
+
+	// The new host can kick the old one, who is told why
+	guest.send(protocol.KickPlayerRequest{PlayerIndex: 0})
+	var closed protocol.RoomClosedResponse
+	host.expect("roomClosed", &closed)
+	if closed.Code != code || closed.Reason != protocol.ClosedKicked {
+		t.Fatalf("unexpected roomClosed %+v", closed)
+	}
+	for len(state.Players) != 1 {
+		guest.expect("roomState", &state)
+	}
+	host.send(protocol.StartRaceRequest{})
+	host.expect("error", &errResp)
+	if errResp.Message != ErrNotInRoom.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotInRoom, errResp.Message)
+	}
+}
+
+func TestHostMigration(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = time.Minute
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	players := make([]*testClient, 3)
+	for i := range players {
+		players[i] = dial(t, srv)
+		players[i].send(protocol.JoinRoomRequest{Code: code})
+		players[i].expect("roomJoined", nil)
+	}
+
+	// Leaving the lobby passes the role to the next player to arrive
+	host.conn.Close()
+	var state protocol.RoomStateResponse
+	for len(state.Players) != 3 {
+		players[2].expect("roomState", &state)
+	}
+	if !state.Players[0].Host || state.Players[0].Index != 1 {
+		t.Fatalf("expected player 1 to host, got %+v", state.Players)
+	}
+
+	// Mid-race the slot is held, but the role goes to someone connected
+	players[0].send(protocol.StartRaceRequest{})
+	players[2].expect("racePhase", nil)
+	players[0].conn.Close()
+	for state.Players[0].Host {
+		players[2].expect("roomState", &state)
+	}
+	if len(state.Players) != 3 || !state.Players[1].Host || state.Players[1].Index != 2 {
+		t.Fatalf("expected player 2 to host, got %+v", state.Players)
+	}
+}
+
+func TestLeaveRoom(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	first := host.createRoom()
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: first})
+	guest.expect("roomJoined", nil)
+
+	var state protocol.RoomStateResponse
+	for len(state.Players) != 2 {
+		host.expect("roomState", &state)
+	}
+	guest.send(protocol.LeaveRoomRequest{})
+	for len(state.Players) != 1 {
+		host.expect("roomState", &state)
+	}
+
+	// Creating or joining another room leaves the current one
+	guest.send(protocol.JoinRoomRequest{Code: first})
+	guest.expect("roomJoined", nil)
+	for len(state.Players) != 2 {
+		host.expect("roomState", &state)
+	}
+	second := guest.createRoom()
+	for len(state.Players) != 1 {
+		host.expect("roomState", &state)
+	}
+	host.send(protocol.JoinRoomRequest{Code: second})
+	host.expect("roomJoined", nil)
+	for state.Code != second || len(state.Players) != 2 {
+		host.expect("roomState", &state)
+	}
+	if n := roomManager.GetRoomClients(first); n != 0 {
+		t.Fatalf("expected room %s to be closed, it has %d clients", first, n)
+	}
+
+	// Leaving twice is harmless
+	guest.send(protocol.LeaveRoomRequest{})
+	guest.send(protocol.LeaveRoomRequest{})
+	for len(state.Players) != 1 {
+		host.expect("roomState", &state)
+	}
+}
+
+func TestAutoStart(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = time.Minute
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	var errResp protocol.ErrorResponse
+	guest.send(protocol.SetAutoStartRequest{AutoStart: protocol.AutoStart{WhenAllReady: true, Delay: 5}})
+	guest.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonNotHost {
+		t.Fatalf("expected notHost, got %q", errResp.Reason)
+	}
+
+	host.send(protocol.SetAutoStartRequest{AutoStart: protocol.AutoStart{WhenAllReady: true, Delay: 5}})
+	var state protocol.RoomStateResponse
+	for !state.AutoStart.WhenAllReady {
+		guest.expect("roomState", &state)
+	}
+
+	// The countdown only begins once everyone is ready
+	host.send(protocol.SetReadyRequest{Ready: true})
+	for !state.Players[0].Ready {
+		guest.expect("roomState", &state)
+	}
+	if state.StartingIn != 0 {
+		t.Fatalf("expected no countdown with one player ready, got %ds", state.StartingIn)
+	}
+	guest.send(protocol.SetReadyRequest{Ready: true})
+	for !state.Players[1].Ready {
+		guest.expect("roomState", &state)
+	}
+	if state.StartingIn != 5 {
+		t.Fatalf("expected a 5s countdown, got %ds", state.StartingIn)
+	}
+
+	// Anyone backing out cancels it
+	guest.send(protocol.SetReadyRequest{Ready: false})
+	for state.Players[1].Ready {
+		guest.expect("roomState", &state)
+	}
+	if state.StartingIn != 0 {
+		t.Fatalf("expected the countdown to be cancelled, got %ds", state.StartingIn)
+	}
+
+	// Reaching the player count starts the race when the timer fires,
+	// once everyone who backed out is ready again
+	host.send(protocol.SetAutoStartRequest{AutoStart: protocol.AutoStart{MinPlayers: 2, Delay: 5}})
+	for state.AutoStart.MinPlayers != 2 {
+		guest.expect("roomState", &state)
+	}
+	if state.StartingIn != 0 {
+		t.Fatalf("expected the countdown to stay cancelled, got %ds", state.StartingIn)
+	}
+	host.send(protocol.SetReadyRequest{Ready: false})
+	for state.Players[0].Ready {
+		guest.expect("roomState", &state)
+	}
+	guest.send(protocol.SetReadyRequest{Ready: true})
+	for !state.Players[1].Ready {
+		guest.expect("roomState", &state)
+	}
+	if state.StartingIn != 0 {
+		t.Fatalf("expected the host to hold the countdown back, got %ds", state.StartingIn)
+	}
+	host.send(protocol.SetReadyRequest{Ready: true})
+	for !state.Players[0].Ready {
+		guest.expect("roomState", &state)
+	}
+	if state.StartingIn != 5 {
+		t.Fatalf("expected a 5s countdown, got %ds", state.StartingIn)
+	}
+	roomManager.mu.RLock()
+	at := roomManager.rooms[code].autoStartAt
+	roomManager.mu.RUnlock()
+	roomManager.autoStartRace(code, at)
+
+	var phase protocol.RacePhaseResponse
+	guest.expect("racePhase", &phase)
+	if phase.Phase != protocol.PhaseCountdown {
+		t.Fatalf("expected countdown, got %s", phase.Phase)
+	}
+	guest.send(protocol.SetReadyRequest{Ready: true})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrRaceStarted.Error() {
+		t.Fatalf("expected %q, got %q", ErrRaceStarted, errResp.Message)
+	}
+}
+
+func TestChat(t *testing.T) {
+	interval := ChatInterval
+	ChatInterval = time.Hour
+	t.Cleanup(func() { ChatInterval = interval })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	host.send(protocol.CreateRoomRequest{Name: "Ada"})
+	var created protocol.RoomCreatedResponse
+	host.expect("roomCreated", &created)
+
+	var errResp protocol.ErrorResponse
+	outsider := dial(t, srv)
+	outsider.send(protocol.ChatRequest{Text: "hello?"})
+	outsider.expect("error", &errResp)
+	if errResp.Message != ErrNotInRoom.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotInRoom, errResp.Message)
+	}
+
+	// Senders hear their own message back, cleaned up and stamped
+	host.send(protocol.ChatRequest{Text: "gl\x07   hf"})
+	var chat protocol.ChatResponse
+	host.expect("chatMessage", &chat)
+	if m := chat.Message; m.Text != "gl hf" || m.Name != "Ada" || m.PlayerIndex != 0 || m.SentAt == 0 {
+		t.Fatalf("unexpected chat message %+v", m)
+	}
+	host.send(protocol.ChatRequest{Text: "again"})
+	host.expect("error", &errResp)
+	if errResp.Message != ErrChatTooFast.Error() {
+		t.Fatalf("expected %q, got %q", ErrChatTooFast, errResp.Message)
+	}
+
+	// Late joiners are caught up on recent messages
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: created.Code})
+	var history protocol.ChatHistoryResponse
+	guest.expect("chatHistory", &history)
+	if len(history.Messages) != 1 || history.Messages[0] != chat.Message {
+		t.Fatalf("unexpected history %+v", history.Messages)
+	}
+}
+
+func TestRoomSettings(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	settings := protocol.RoomSettings{MaxPlayers: 3, Countdown: 5}
+	var errResp protocol.ErrorResponse
+	guest.send(protocol.SetSettingsRequest{Settings: settings})
+	guest.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonNotHost {
+		t.Fatalf("expected notHost, got %q", errResp.Reason)
+	}
+	host.send(protocol.SetSettingsRequest{Settings: protocol.RoomSettings{MaxPlayers: 3, Language: "tlh"}})
+	host.expect("error", &errResp)
+	if errResp.Message != ErrUnknownLanguage.Error() {
+		t.Fatalf("expected %q, got %q", ErrUnknownLanguage, errResp.Message)
+	}
+
+	host.send(protocol.SetSettingsRequest{Settings: settings})
+	var state protocol.RoomStateResponse
+	for state.Settings != settings {
+		guest.expect("roomState", &state)
+	}
+
+	// The room fills up at its own limit, which can't then drop
+	// below the number of players already in it
+	third := dial(t, srv)
+	third.send(protocol.JoinRoomRequest{Code: code})
+	third.expect("roomJoined", nil)
+	late := dial(t, srv)
+	late.send(protocol.JoinRoomRequest{Code: code})
+	late.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonRoomFull {
+		t.Fatalf("expected roomFull, got %q", errResp.Reason)
+	}
+	host.send(protocol.SetSettingsRequest{Settings: protocol.RoomSettings{MaxPlayers: 2}})
+	host.expect("error", &errResp)
+	if errResp.Message != ErrTooManyPlayers.Error() {
+		t.Fatalf("expected %q, got %q", ErrTooManyPlayers, errResp.Message)
+	}
+
+	// Races use the room's countdown and passage formatting
+	host.send(protocol.StartRaceRequest{})
+	var phase protocol.RacePhaseResponse
+	guest.expect("racePhase", &phase)
+	if phase.Countdown != 5 {
+		t.Fatalf("expected a 5s countdown, got %d", phase.Countdown)
+	}
+	if text := phase.Passage.Text; strings.ContainsAny(text, ".,;!?'0123456789") || text != strings.ToLower(text) {
+		t.Fatalf("expected plain lowercase words, got %q", text)
+	}
+}
+
+func TestRematch(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = 50 * time.Millisecond
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	var errResp protocol.ErrorResponse
+	guest.send(protocol.VoteRematchRequest{Vote: true})
+	guest.expect("error", &errResp)
+	if errResp.Message != ErrNoRematch.Error() {
+		t.Fatalf("expected %q, got %q", ErrNoRematch, errResp.Message)
+	}
+
+	host.send(protocol.StartRaceRequest{})
+	var phase protocol.RacePhaseResponse
+	host.expect("racePhase", &phase)
+	first := phase.Passage.ID
+	for phase.Phase != string(PhaseRacing) {
+		host.expect("racePhase", &phase)
+	}
+	guest.finishRace(code)
+	host.expect("playerFinished", nil)
+	host.finishRace(code)
+
+	// The winner of a two player race scores two points, the runner-up one
+	var results protocol.RaceResultsResponse
+	guest.expect("raceResults", &results)
+	if r := results.Results[0]; r.PlayerIndex != 1 || r.Points != 2 || r.TotalPoints != 2 {
+		t.Fatalf("expected guest to score 2 points, got %+v", r)
+	}
+	if r := results.Results[1]; r.Points != 1 || r.TotalPoints != 1 {
+		t.Fatalf("expected host to score 1 point, got %+v", r)
+	}
+
+	guest.send(protocol.VoteRematchRequest{Vote: true})
+	var state protocol.RoomStateResponse
+	for len(state.Players) < 2 || !state.Players[1].Rematch {
+		host.expect("roomState", &state)
+	}
+	guest.send(protocol.AcceptRematchRequest{})
+	guest.expect("error", &errResp)
+	if errResp.Reason != protocol.ReasonNotHost {
+		t.Fatalf("expected notHost, got %q", errResp.Reason)
+	}
+
+	// Accepting returns everyone to the same room with their points
+	host.send(protocol.AcceptRematchRequest{})
+	guest.expect("racePhase", &phase)
+	if phase.Phase != string(PhaseWaiting) || phase.Code != code {
+		t.Fatalf("expected room %s to wait, got %+v", code, phase)
+	}
+	for state.Players[1].Rematch {
+		guest.expect("roomState", &state)
+	}
+	if state.Rounds != 1 || state.Players[0].Points != 1 || state.Players[1].Points != 2 {
+		t.Fatalf("expected points to carry over, got %+v", state)
+	}
+
+	// The next race brings a different passage
+	host.send(protocol.StartRaceRequest{})
+	guest.expect("racePhase", &phase)
+	if phase.Passage == nil || phase.Passage.ID == first {
+		t.Fatalf("expected a fresh passage, got %+v", phase.Passage)
+	}
+}
+
+func TestQuickMatch(t *testing.T) {
+	wait := QuickMatchWait
+	QuickMatchWait = 100 * time.Millisecond
+	t.Cleanup(func() { QuickMatchWait = wait })
+	srv := newTestServer(t)
+
+	// A lone player gets a room of their own once the wait runs out,
+	// which counts down straight away
+	first := dial(t, srv)
+	first.send(protocol.QuickMatchRequest{Name: "First"})
+	var status protocol.QueueStatusResponse
+	first.expect("queueStatus", &status)
+	if status.Waiting != 1 || status.MatchIn < 1 {
+		t.Fatalf("expected to wait alone, got %+v", status)
+	}
+	var joined protocol.RoomJoinedResponse
+	first.expect("roomJoined", &joined)
+	var state protocol.RoomStateResponse
+	first.expect("roomState", &state)
+	if !state.Settings.Public || state.StartingIn != QuickMatchDelay {
+		t.Fatalf("expected a public room counting down, got %+v", state)
+	}
+
+	// Later players join the open room rather than queueing
+	second := dial(t, srv)
+	second.send(protocol.QuickMatchRequest{})
+	var joinedSecond protocol.RoomJoinedResponse
+	second.expect("roomJoined", &joinedSecond)
+	if joinedSecond.Code != joined.Code {
+		t.Fatalf("expected to join room %s, got %s", joined.Code, joinedSecond.Code)
+	}
+	for len(state.Players) != 2 {
+		first.expect("roomState", &state)
+	}
+	if state.StartingIn < 1 || state.StartingIn > QuickMatchDelay {
+		t.Fatalf("expected the countdown to carry on, got %ds", state.StartingIn)
+	}
+
+	// Once that room is closed to newcomers, players queue again and
+	// can leave the queue before being matched
+	QuickMatchWait = time.Minute
+	first.send(protocol.LockRoomRequest{Locked: true})
+	for !state.Locked {
+		first.expect("roomState", &state)
+	}
+	quitter := dial(t, srv)
+	quitter.send(protocol.QuickMatchRequest{})
+	quitter.expect("queueStatus", &status)
+	quitter.send(protocol.LeaveRoomRequest{})
+	// Chatting outside a room fails, confirming the leave went through
+	quitter.send(protocol.ChatRequest{Text: "bye"})
+	quitter.expect("error", nil)
+
+	third := dial(t, srv)
+	third.send(protocol.QuickMatchRequest{})
+	third.expect("queueStatus", &status)
+	if status.Waiting != 1 {
+		t.Fatalf("expected the queue to hold 1 player, got %d", status.Waiting)
+	}
+	fourth := dial(t, srv)
+	fourth.send(protocol.QuickMatchRequest{})
+	var joinedThird, joinedFourth protocol.RoomJoinedResponse
+	third.expect("roomJoined", &joinedThird)
+	fourth.expect("roomJoined", &joinedFourth)
+	if joinedThird.Code != joinedFourth.Code || joinedThird.Code == joined.Code {
+		t.Fatalf("expected a new room for both, got %s and %s", joinedThird.Code, joinedFourth.Code)
+	}
+	fourth.expect("roomState", &state)
+	if len(state.Players) != 2 {
+		t.Fatalf("expected 2 players, got %d", len(state.Players))
+	}
+}
+
+// dialAs connects to the test server as the player with the given ID
+func dialAs(t *testing.T, srv *httptest.Server, playerID string) *testClient {
+	t.Helper()
+	c := dialRaw(t, srv)
+	c.send(protocol.HelloRequest{ProtocolVersion: protocol.Version, Capabilities: Capabilities, PlayerID: playerID})
+	c.expect("welcome", nil)
+	return c
+}
+
+func TestRatings(t *testing.T) {
+	countdown, interval, store := CountdownDuration, RankedInterval, ratingStore
+	CountdownDuration = 50 * time.Millisecond
+	RankedInterval = 20 * time.Millisecond
+	t.Cleanup(func() {
+		// The ranked queue may still be rematching in the background
+		roomManager.mu.Lock()
+		defer roomManager.mu.Unlock()
+		CountdownDuration, RankedInterval, ratingStore = countdown, interval, store
+	})
+	srv := newTestServer(t)
+
+	path := filepath.Join(t.TempDir(), "ratings.json")
+	if err := os.WriteFile(path, []byte(`{"pro": {"rating": 1800, "races": 40}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadRatings(path); err != nil {
+		t.Fatalf("LoadRatings: %v", err)
+	}
+
+	// Ranked players are only matched with others of a similar rating
+	pro := dialAs(t, srv, "pro")
+	pro.send(protocol.QuickMatchRequest{Ranked: true})
+	var status protocol.QueueStatusResponse
+	pro.expect("queueStatus", &status)
+	alice := dialAs(t, srv, "alice")
+	alice.send(protocol.QuickMatchRequest{Name: "Alice", Ranked: true})
+	alice.expect("queueStatus", nil)
+	bob := dialAs(t, srv, "bob")
+	bob.send(protocol.QuickMatchRequest{Name: "Bob", Ranked: true})
+
+	var joinedAlice, joinedBob protocol.RoomJoinedResponse
+	alice.expect("roomJoined", &joinedAlice)
+	bob.expect("roomJoined", &joinedBob)
+	if joinedAlice.Code != joinedBob.Code {
+		t.Fatalf("expected one room, got %s and %s", joinedAlice.Code, joinedBob.Code)
+	}
+	for status.Waiting != 1 {
+		pro.expect("queueStatus", &status)
+	}
+
+	var state protocol.RoomStateResponse
+	bob.expect("roomState", &state)
+	if len(state.Players) != 2 || state.Players[1].Rating != ratings.Initial {
+		t.Fatalf("expected new players to start at %d, got %+v", ratings.Initial, state.Players)
+	}
+
+	// Evenly rated players move half of K either way
+	alice.send(protocol.StartRaceRequest{})
+	var phase protocol.RacePhaseResponse
+	for phase.Phase != string(PhaseRacing) {
+		bob.expect("racePhase", &phase)
+	}
+	bob.finishRace(joinedBob.Code)
+	alice.expect("playerFinished", nil)
+	alice.finishRace(joinedAlice.Code)
+
+	var results protocol.RaceResultsResponse
+	alice.expect("raceResults", &results)
+	if r := results.Results[0]; r.Name != "Bob" || r.Rating != ratings.Initial+ratings.K/2 || r.RatingChange != ratings.K/2 {
+		t.Fatalf("expected Bob to gain %d, got %+v", ratings.K/2, r)
+	}
+	if r := results.Results[1]; r.RatingChange != -ratings.K/2 {
+		t.Fatalf("expected Alice to lose %d, got %+v", ratings.K/2, r)
+	}
+	for len(state.Players) < 2 || state.Players[0].RatingChange == 0 {
+		alice.expect("roomState", &state)
+	}
+	if state.Players[0].Rating != ratings.Initial-ratings.K/2 {
+		t.Fatalf("expected the roster to show the new rating, got %+v", state.Players[0])
+	}
+
+	// Leaving mid-race is rated as coming last
+	alice.send(protocol.AcceptRematchRequest{})
+	for phase.Phase != string(PhaseWaiting) {
+		alice.expect("racePhase", &phase)
+	}
+	alice.send(protocol.StartRaceRequest{})
+	for phase.Phase != string(PhaseRacing) {
+		alice.expect("racePhase", &phase)
+	}
+	bob.send(protocol.LeaveRoomRequest{})
+	for len(state.Players) != 1 {
+		alice.expect("roomState", &state)
+	}
+	alice.finishRace(joinedAlice.Code)
+	alice.expect("raceResults", &results)
+	if len(results.Results) != 1 || results.Results[0].RatingChange <= 0 {
+		t.Fatalf("expected Alice to gain from Bob leaving, got %+v", results.Results)
+	}
+	bobRating := ratingStore.Get("bob")
+	if bobRating >= ratings.Initial+ratings.K/2 {
+		t.Fatalf("expected Bob to lose rating for leaving, got %d", bobRating)
+	}
+
+	// Races outside the ranked queue leave ratings alone
+	host := dialAs(t, srv, "carol")
+	code := host.createRoom()
+	guest := dialAs(t, srv, "dave")
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+	host.send(protocol.StartRaceRequest{})
+	phase = protocol.RacePhaseResponse{}
+	for phase.Phase != string(PhaseRacing) {
+		guest.expect("racePhase", &phase)
+	}
+	guest.finishRace(code)
+	host.expect("playerFinished", nil)
+	host.finishRace(code)
+	host.expect("raceResults", &results)
+	for _, r := range results.Results {
+		if r.Rating != 0 || r.RatingChange != 0 {
+			t.Fatalf("expected an unranked race to carry no ratings, got %+v", r)
+		}
+	}
+	if carol, dave := ratingStore.Get("carol"), ratingStore.Get("dave"); carol != ratings.Initial || dave != ratings.Initial {
+		t.Fatalf("expected unranked players to keep %d, got %d and %d", ratings.Initial, carol, dave)
+	}
+
+	// Ratings outlive the server
+	if err := CloseRatings(); err != nil {
+		t.Fatalf("CloseRatings: %v", err)
+	}
+	saved, err := ratings.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Get("bob") != bobRating || saved.Get("pro") != 1800 {
+		t.Fatalf("expected saved ratings, got bob %d and pro %d", saved.Get("bob"), saved.Get("pro"))
+	}
+}
+
+func TestSpectators(t *testing.T) {
+	countdown := CountdownDuration
+	CountdownDuration = 50 * time.Millisecond
+	t.Cleanup(func() { CountdownDuration = countdown })
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code})
+	guest.expect("roomJoined", nil)
+
+	// Spectators take no slot and cannot act in the room
+	watcher := dial(t, srv)
+	watcher.send(protocol.SpectateRoomRequest{Code: code})
+	var watching protocol.SpectatingResponse
+	watcher.expect("spectating", &watching)
+	if watching.Code != code || watching.Passage != nil {
+		t.Fatalf("unexpected spectating response %+v", watching)
+	}
+	var state protocol.RoomStateResponse
+	watcher.expect("roomState", &state)
+	if !state.Spectating || state.YourIndex != -1 || len(state.Players) != 2 {
+		t.Fatalf("expected to watch 2 players, got %+v", state)
+	}
+	for state.Spectators != 1 {
+		host.expect("roomState", &state)
+	}
+	var errResp protocol.ErrorResponse
+	watcher.send(protocol.SetReadyRequest{Ready: true})
+	watcher.expect("error", &errResp)
+	if errResp.Message != ErrNotInRoom.Error() {
+		t.Fatalf("expected %q, got %q", ErrNotInRoom, errResp.Message)
+	}
+
+	// They follow the race, and anyone joining mid-race watches too
+	host.send(protocol.StartRaceRequest{})
+	var phase protocol.RacePhaseResponse
+	for phase.Phase != string(PhaseRacing) {
+		watcher.expect("racePhase", &phase)
+	}
+	late := dial(t, srv)
+	late.send(protocol.JoinRoomRequest{Code: code})
+	late.expect("spectating", &watching)
+	if watching.Phase != string(PhaseRacing) || watching.Passage == nil {
+		t.Fatalf("expected to watch the race under way, got %+v", watching)
+	}
+	guest.send(protocol.ProgressRequest{Position: 3, WPM: 40})
+	var progress protocol.PlayerProgressResponse
+	watcher.expect("playerProgress", &progress)
+	if progress.PlayerIndex != 1 || progress.Position != 3 {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+	guest.finishRace(code)
+	host.finishRace(code)
+	late.expect("raceResults", nil)
+
+	// Between races a spectator can take a slot
+	late.send(protocol.JoinRoomRequest{Code: code})
+	late.expect("roomJoined", nil)
+	for len(state.Players) != 3 {
+		watcher.expect("roomState", &state)
+	}
+	if state.Spectators != 1 {
+		t.Fatalf("expected 1 spectator left, got %d", state.Spectators)
+	}
+
+	// Spectators are told when the last player leaves
+	for _, c := range []*testClient{host, guest, late} {
+		c.send(protocol.LeaveRoomRequest{})
+	}
+	var closed protocol.RoomClosedResponse
+	watcher.expect("roomClosed", &closed)
+	if closed.Reason != protocol.ClosedEmpty {
+		t.Fatalf("expected %q, got %q", protocol.ClosedEmpty, closed.Reason)
+	}
+}
+
+func TestRoomBrowser(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	code := host.createRoom()
+	private := dial(t, srv)
+	private.createRoom()
+
+	var list protocol.RoomListResponse
+	host.send(protocol.ListRoomsRequest{})
+	host.expect("roomList", &list)
+	if len(list.Rooms) != 0 {
+		t.Fatalf("expected no public rooms, got %+v", list.Rooms)
+	}
+
+	settings := protocol.RoomSettings{MaxPlayers: 4, Public: true}
+	host.send(protocol.SetSettingsRequest{Settings: settings})
+	var state protocol.RoomStateResponse
+	for !state.Settings.Public {
+		host.expect("roomState", &state)
+	}
+	guest := dial(t, srv)
+	guest.send(protocol.JoinRoomRequest{Code: code, Name: "Grace"})
+	guest.expect("roomJoined", nil)
+
+	guest.send(protocol.ListRoomsRequest{})
+	guest.expect("roomList", &list)
+	if len(list.Rooms) != 1 {
+		t.Fatalf("expected one public room, got %+v", list.Rooms)
+	}
+	want := protocol.RoomSummary{Code: code, Host: "Player 1", PlayerCount: 2, Phase: protocol.PhaseWaiting, Settings: settings}
+	if list.Rooms[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, list.Rooms[0])
+	}
+
+	// The same list is served over HTTP
+	rec := httptest.NewRecorder()
+	HandleListRooms(rec, httptest.NewRequest(http.MethodGet, "/api/rooms", nil))
+	var served protocol.RoomListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(served, list) {
+		t.Fatalf("expected %+v over HTTP, got %+v", list, served)
+	}
+
+	// Locked rooms are left out
+	host.send(protocol.LockRoomRequest{Locked: true})
+	for !state.Locked {
+		host.expect("roomState", &state)
+	}
+	rec = httptest.NewRecorder()
+	HandleListRooms(rec, httptest.NewRequest(http.MethodGet, "/api/rooms", nil))
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"rooms":[]}` {
+		t.Fatalf("expected no rooms once locked, got %s", body)
+	}
+}
+
+func TestRoomPassword(t *testing.T) {
+	srv := newTestServer(t)
+
+	host := dial(t, srv)
+	host.send(protocol.CreateRoomRequest{Password: "hunter2"})
+	var created protocol.RoomCreatedResponse
+	host.expect("roomCreated", &created)
+	code := created.Code
+
+	// Without the password the room can't be joined or watched
+	guest := dial(t, srv)
+	var errResp protocol.ErrorResponse
+	for _, req := range []protocol.Message{
+		protocol.JoinRoomRequest{Code: code},
+		protocol.JoinRoomRequest{Code: code, Password: "hunter3"},
+		protocol.SpectateRoomRequest{Code: code, Password: "hunter"},
+	} {
+		guest.send(req)
+		guest.expect("error", &errResp)
+		if errResp.Reason != protocol.ReasonWrongPassword {
+			t.Fatalf("%+v: expected wrongPassword, got %q", req, errResp.Reason)
+		}
+	}
+
+	guest.send(protocol.JoinRoomRequest{Code: code, Password: "hunter2"})
+	guest.expect("roomJoined", nil)
+
+	// A spectator let in with the password can join without it
+	watcher := dial(t, srv)
+	watcher.send(protocol.SpectateRoomRequest{Code: code, Password: "hunter2"})
+	watcher.expect("spectating", nil)
+	watcher.send(protocol.JoinRoomRequest{Code: code})
+	watcher.expect("roomJoined", nil)
+}
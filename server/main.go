@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -13,6 +14,32 @@ import (
 )
 
 func main() {
+	passagesDir := flag.String("passages", "", "directory of additional passage files (.json or .txt)")
+	ratingsFile := flag.String("ratings", "ratings.json", "file player ratings are kept in, or empty to keep them in memory")
+	flag.DurationVar(&handlers.PingPeriod, "ping-period", handlers.PingPeriod, "how often each client is pinged")
+	flag.DurationVar(&handlers.PongWait, "pong-wait", handlers.PongWait, "how long a client may go unheard before it is dropped")
+	flag.DurationVar(&handlers.WriteWait, "write-wait", handlers.WriteWait, "how long a single write to a client may block")
+	flag.DurationVar(&handlers.RoomIdleTimeout, "room-idle-timeout", handlers.RoomIdleTimeout, "how long a room may go unused before it is closed")
+	flag.Parse()
+
+	if handlers.PingPeriod <= 0 || handlers.WriteWait <= 0 || handlers.RoomIdleTimeout <= 0 {
+		log.Fatalf("Intervals must be positive")
+	}
+	if handlers.PingPeriod >= handlers.PongWait {
+		log.Fatalf("-ping-period (%s) must be shorter than -pong-wait (%s)", handlers.PingPeriod, handlers.PongWait)
+	}
+
+	if *passagesDir != "" {
+		if err := handlers.LoadPassages(*passagesDir); err != nil {
+			log.Fatalf("Failed to load passages: %v", err)
+		}
+	}
+	if *ratingsFile != "" {
+		if err := handlers.LoadRatings(*ratingsFile); err != nil {
+			log.Fatalf("Failed to load ratings: %v", err)
+		}
+	}
+
 	mux := http.NewServeMux()
 	httpServer := &http.Server{
 		Addr:    ":3000",
@@ -27,6 +54,10 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	mux.HandleFunc("/api/rooms", handlers.HandleListRooms)
+
+	// Close rooms nobody has used in a while
+	stopJanitor := handlers.StartRoomJanitor(time.Minute)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -34,12 +65,16 @@ func main() {
 	go func() {
 		<-sigChan
 		log.Println("Gracefully shutting down...")
+		stopJanitor()
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		if err := httpServer.Shutdown(ctx); err != nil {
 			log.Fatalf("HTTP server did not close gracefully: %v", err)
 		}
+		if err := handlers.CloseRatings(); err != nil {
+			log.Printf("Failed to save ratings: %v", err)
+		}
 
 		os.Exit(0)
 	}()
@@ -0,0 +1,307 @@
+// Package passages provides the library of texts
+// that players race to type
+package passages
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed data/*.json
+var embedded embed.FS
+
+// Length buckets passages by word count
+type Length string
+
+const (
+	Short  Length = "short"
+	Medium Length = "medium"
+	Long   Length = "long"
+)
+
+// Difficulty buckets passages by how hard they are to type
+type Difficulty string
+
+const (
+	Easy   Difficulty = "easy"
+	Normal Difficulty = "normal"
+	Hard   Difficulty = "hard"
+)
+
+// DefaultLanguage is assumed for passages that do not name a language
+const DefaultLanguage = "en"
+
+var ErrNoPassages = errors.New("no passages match the requested filter")
+
+// Passage is a single text to race on
+type Passage struct {
+	ID         string     `json:"id"`
+	Text       string     `json:"text"`
+	Source     string     `json:"source"`
+	Author     string     `json:"author"`
+	Difficulty Difficulty `json:"difficulty,omitempty"`
+	Language   string     `json:"language,omitempty"`
+}
+
+// Length reports which length bucket the passage falls in
+func (p Passage) Length() Length {
+	words := len(strings.Fields(p.Text))
+	switch {
+	case words < 20:
+		return Short
+	case words < 45:
+		return Medium
+	default:
+		return Long
+	}
+}
+
+// Format returns the passage with punctuation and symbols, or words
+// containing digits, removed when they are not wanted. A passage
+// that would be left empty is returned unchanged.
+func (p Passage) Format(punctuation, numbers bool) Passage {
+	if punctuation && numbers {
+		return p
+	}
+
+	var words []string
+	for _, word := range strings.Fields(p.Text) {
+		if !numbers && strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			continue
+		}
+		if !punctuation {
+			word = strings.Map(func(r rune) rune {
+				if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+					return -1
+				}
+				return unicode.ToLower(r)
+			}, word)
+		}
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) > 0 {
+		p.Text = strings.Join(words, " ")
+	}
+	return p
+}
+
+// estimateDifficulty grades a passage by its average word
+// length and how much punctuation it contains
+func estimateDifficulty(text string) Difficulty {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return Easy
+	}
+
+	letters, symbols := 0, 0
+	for _, r := range text {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letters++
+		case r != ' ':
+			symbols++
+		}
+	}
+
+	score := float64(letters)/float64(len(words)) + 10*float64(symbols)/float64(len(words))
+	switch {
+	case score < 5:
+		return Easy
+	case score < 6:
+		return Normal
+	default:
+		return Hard
+	}
+}
+
+// Filter narrows the passages a race can be run on.
+// Empty fields match any passage.
+type Filter struct {
+	Length     Length
+	Difficulty Difficulty
+	Language   string
+	// AvoidID is skipped unless it is the only passage that matches,
+	// so the same passage isn't raced twice in a row
+	AvoidID string
+}
+
+func (f Filter) matches(p Passage) bool {
+	if f.Length != "" && p.Length() != f.Length {
+		return false
+	}
+	if f.Difficulty != "" && p.Difficulty != f.Difficulty {
+		return false
+	}
+	if f.Language != "" && p.Language != f.Language {
+		return false
+	}
+	return true
+}
+
+// Library is a concurrency-safe collection of passages
+type Library struct {
+	passages []Passage
+	byID     map[string]int
+	mu       sync.RWMutex
+}
+
+// NewLibrary creates a library preloaded with the embedded passages
+func NewLibrary() (*Library, error) {
+	lib := &Library{byID: make(map[string]int)}
+
+	files, err := embedded.ReadDir("data")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := embedded.ReadFile("data/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := lib.addJSON(data); err != nil {
+			return nil, fmt.Errorf("embedded %s: %w", file.Name(), err)
+		}
+	}
+	return lib, nil
+}
+
+// LoadDir adds every passage found in dir. Files ending in .json hold
+// an array of passages; any .txt file is a single passage whose ID is
+// its file name. Passages with an existing ID replace the original.
+func (l *Library) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		switch filepath.Ext(entry.Name()) {
+		case ".json":
+			if err := l.addJSON(data); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		case ".txt":
+			id := strings.TrimSuffix(entry.Name(), ".txt")
+			l.Add(Passage{ID: id, Text: string(data), Source: id})
+		}
+	}
+	return nil
+}
+
+func (l *Library) addJSON(data []byte) error {
+	var passages []Passage
+	if err := json.Unmarshal(data, &passages); err != nil {
+		return err
+	}
+	for _, p := range passages {
+		if p.ID == "" {
+			return errors.New("passage is missing an id")
+		}
+		l.Add(p)
+	}
+	return nil
+}
+
+// Add inserts a passage, replacing any existing passage with the same ID
+func (l *Library) Add(p Passage) {
+	p.Text = strings.Join(strings.Fields(p.Text), " ")
+	if p.Difficulty == "" {
+		p.Difficulty = estimateDifficulty(p.Text)
+	}
+	if p.Language == "" {
+		p.Language = DefaultLanguage
+	}
+	if p.Text == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i, exists := l.byID[p.ID]; exists {
+		l.passages[i] = p
+		return
+	}
+	l.byID[p.ID] = len(l.passages)
+	l.passages = append(l.passages, p)
+}
+
+// Len returns the number of passages in the library
+func (l *Library) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.passages)
+}
+
+// Languages returns the languages passages are available in, sorted
+func (l *Library) Languages() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var languages []string
+	for _, p := range l.passages {
+		if !seen[p.Language] {
+			seen[p.Language] = true
+			languages = append(languages, p.Language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Get returns the passage with the given ID
+func (l *Library) Get(id string) (Passage, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if i, exists := l.byID[id]; exists {
+		return l.passages[i], true
+	}
+	return Passage{}, false
+}
+
+// Pick returns a random passage matching the filter
+func (l *Library) Pick(filter Filter) (Passage, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var candidates, avoided []Passage
+	for _, p := range l.passages {
+		switch {
+		case !filter.matches(p):
+		case p.ID == filter.AvoidID:
+			avoided = append(avoided, p)
+		default:
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = avoided
+	}
+	if len(candidates) == 0 {
+		return Passage{}, ErrNoPassages
+	}
+	return candidates[rand.Intn(len(candidates))], nil
+}
@@ -0,0 +1,104 @@
+package passages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbeddedLibrary(t *testing.T) {
+	lib, err := NewLibrary()
+	if err != nil {
+		t.Fatalf("NewLibrary: %v", err)
+	}
+	if lib.Len() == 0 {
+		t.Fatal("expected embedded passages")
+	}
+
+	for _, length := range []Length{Short, Medium, Long} {
+		p, err := lib.Pick(Filter{Length: length})
+		if err != nil {
+			t.Fatalf("Pick(%s): %v", length, err)
+		}
+		if p.Length() != length {
+			t.Fatalf("Pick(%s) returned %s passage %q", length, p.Length(), p.ID)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "custom.txt"), []byte("  Short and\nsweet.  "), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "more.json"), []byte(`[{"id":"walden","text":"Replaced.","source":"Walden","author":"Thoreau","difficulty":"hard"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := NewLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := lib.Len()
+	if err := lib.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if lib.Len() != before+1 {
+		t.Fatalf("expected %d passages, got %d", before+1, lib.Len())
+	}
+
+	if p, _ := lib.Get("walden"); p.Text != "Replaced." || p.Difficulty != Hard {
+		t.Fatalf("expected walden to be replaced, got %+v", p)
+	}
+	if p, ok := lib.Get("custom"); !ok || p.Text != "Short and sweet." {
+		t.Fatalf("expected custom passage with normalized text, got %+v", p)
+	}
+}
+
+func TestLanguagesAndFormat(t *testing.T) {
+	lib, err := NewLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib.Add(Passage{ID: "hola", Text: "Hola, ¿qué tal?", Language: "es"})
+
+	if got := lib.Languages(); len(got) != 2 || got[0] != "en" || got[1] != "es" {
+		t.Fatalf("expected [en es], got %v", got)
+	}
+	if p, err := lib.Pick(Filter{Language: "es"}); err != nil || p.ID != "hola" {
+		t.Fatalf("expected the Spanish passage, got %+v (%v)", p, err)
+	}
+
+	p := Passage{Text: "Call me at 555-1234, Ishmael. It's 1851!"}
+	tests := []struct {
+		punctuation, numbers bool
+		want                 string
+	}{
+		{true, true, p.Text},
+		{false, true, "call me at 5551234 ishmael its 1851"},
+		{true, false, "Call me at Ishmael. It's"},
+		{false, false, "call me at ishmael its"},
+	}
+	for _, tt := range tests {
+		if got := p.Format(tt.punctuation, tt.numbers).Text; got != tt.want {
+			t.Errorf("Format(%t, %t) = %q, want %q", tt.punctuation, tt.numbers, got, tt.want)
+		}
+	}
+}
+
+func TestPickAvoids(t *testing.T) {
+	lib := &Library{byID: make(map[string]int)}
+	lib.Add(Passage{ID: "a", Text: "one"})
+	lib.Add(Passage{ID: "b", Text: "two"})
+
+	for i := 0; i < 20; i++ {
+		if p, err := lib.Pick(Filter{AvoidID: "a"}); err != nil || p.ID != "b" {
+			t.Fatalf("expected b, got %+v (%v)", p, err)
+		}
+	}
+	// An avoided passage is still used when nothing else matches
+	lib.Add(Passage{ID: "c", Text: "three", Language: "fr"})
+	if p, err := lib.Pick(Filter{AvoidID: "c", Language: "fr"}); err != nil || p.ID != "c" {
+		t.Fatalf("expected c, got %+v (%v)", p, err)
+	}
+}
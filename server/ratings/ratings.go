@@ -0,0 +1,207 @@
+// Package ratings keeps players' skill ratings, moved by the Elo
+// system after every race they take part in, whether or not they
+// finish it
+package ratings
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// Initial is the rating of a player with no rated races
+	Initial = 1200
+	// K is the most a rating can move in one race
+	K = 32
+)
+
+// Placing is where a player came in a race. Players with the same
+// Rank drew with each other.
+type Placing struct {
+	ID   string
+	Rank int
+}
+
+// Rating is a player's standing
+type Rating struct {
+	Rating int `json:"rating"`
+	Races  int `json:"races"` // rated races played
+}
+
+// Store holds players' ratings, keyed by player ID. A store with a
+// path saves itself there in the background after every race it
+// records.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	ratings map[string]Rating
+
+	dirty   chan struct{} // wakes the saver, nil for stores kept in memory
+	closed  bool
+	stopped chan struct{} // closed once the saver has finished
+	err     error         // from the saver's last write
+}
+
+// NewStore returns an empty store that is kept in memory only
+func NewStore() *Store {
+	return &Store{ratings: make(map[string]Rating)}
+}
+
+// Open loads the ratings saved at path. A missing file is not an
+// error and yields an empty store, created on the first save. Close
+// the store to save anything not yet written.
+func Open(path string) (*Store, error) {
+	s := NewStore()
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &s.ratings); err != nil {
+			return nil, err
+		}
+	}
+	s.dirty = make(chan struct{}, 1)
+	s.stopped = make(chan struct{})
+	go s.saver()
+	return s, nil
+}
+
+// Close waits for the store to be saved and stops saving it, returning
+// any error from the last write. Races recorded afterwards are kept in
+// memory only.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.dirty == nil || s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.dirty)
+	s.mu.Unlock()
+
+	<-s.stopped
+	return s.err
+}
+
+// Len reports how many players have a rating
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ratings)
+}
+
+// Get returns a player's rating
+func (s *Store) Get(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getLocked(id)
+}
+
+func (s *Store) getLocked(id string) int {
+	if r, exists := s.ratings[id]; exists {
+		return r.Rating
+	}
+	return Initial
+}
+
+// expected is the chance the Elo system gives a player rated a of
+// beating one rated b
+func expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// Record rates a race as a set of head-to-head games between every
+// pair of players, and has the store saved. It returns how much each
+// player's rating moved, keyed by ID. Races with fewer than two
+// players change nothing.
+func (s *Store) Record(placings []Placing) map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make(map[string]int, len(placings))
+	if len(placings) < 2 {
+		return changes
+	}
+
+	before := make([]int, len(placings))
+	for i, p := range placings {
+		before[i] = s.getLocked(p.ID)
+	}
+	for i, p := range placings {
+		score := 0.0
+		for j, q := range placings {
+			if i == j {
+				continue
+			}
+			actual := 0.5
+			switch {
+			case p.Rank < q.Rank:
+				actual = 1
+			case p.Rank > q.Rank:
+				actual = 0
+			}
+			score += actual - expected(before[i], before[j])
+		}
+		// Scaling by the field size keeps big races from swinging
+		// ratings more than a head-to-head would
+		change := int(math.Round(K * score / float64(len(placings)-1)))
+		changes[p.ID] = change
+		r := s.ratings[p.ID]
+		s.ratings[p.ID] = Rating{Rating: before[i] + change, Races: r.Races + 1}
+	}
+	if s.dirty != nil && !s.closed {
+		select {
+		case s.dirty <- struct{}{}:
+		default:
+			// A save is already pending and will include this race
+		}
+	}
+	return changes
+}
+
+// saver writes the store to its path whenever it changes, until
+// the store is closed. Races recorded while a save is under way
+// are written by the next one.
+func (s *Store) saver() {
+	defer close(s.stopped)
+	for range s.dirty {
+		s.err = s.save()
+		if s.err != nil {
+			log.Printf("Failed to save ratings: %v", s.err)
+		}
+	}
+}
+
+// save writes a snapshot of the store to its path. The file is
+// replaced in one step so a crash never leaves it half written.
+func (s *Store) save() error {
+	s.mu.Lock()
+	snapshot := maps.Clone(s.ratings)
+	s.mu.Unlock()
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,55 @@
+package ratings
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	s := NewStore()
+
+	// Evenly rated players swing by half of K
+	changes := s.Record([]Placing{{ID: "a", Rank: 1}, {ID: "b", Rank: 2}})
+	if changes["a"] != K/2 || changes["b"] != -K/2 {
+		t.Fatalf("expected ±%d, got %v", K/2, changes)
+	}
+	if s.Get("a") != Initial+K/2 || s.Get("b") != Initial-K/2 {
+		t.Fatalf("unexpected ratings %d and %d", s.Get("a"), s.Get("b"))
+	}
+
+	// Beating a weaker player gains less than losing to them costs
+	changes = s.Record([]Placing{{ID: "a", Rank: 1}, {ID: "b", Rank: 2}})
+	gain := changes["a"]
+	changes = s.Record([]Placing{{ID: "b", Rank: 1}, {ID: "a", Rank: 2}})
+	if loss := -changes["a"]; gain >= loss {
+		t.Fatalf("expected the favourite to gain less than they lose, got +%d and -%d", gain, loss)
+	}
+
+	// A lone racer has nobody to be rated against
+	if changes := s.Record([]Placing{{ID: "c", Rank: 1}}); len(changes) != 0 || s.Get("c") != Initial {
+		t.Fatalf("expected a solo race to be unrated, got %v", changes)
+	}
+}
+
+func TestOpenSavesRatings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings", "ratings.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if s.Len() != 0 {
+		t.Fatalf("expected an empty store, got %d ratings", s.Len())
+	}
+	s.Record([]Placing{{ID: "a", Rank: 1}, {ID: "b", Rank: 2}})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.Len() != 2 || reopened.Get("a") != s.Get("a") {
+		t.Fatalf("expected saved ratings, got %d players rated %d", reopened.Len(), reopened.Get("a"))
+	}
+}